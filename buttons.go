@@ -0,0 +1,16 @@
+package goboy
+
+// A set of buttons, combined with |
+type Buttons byte
+
+// These match the order of the joypad buttons in the emulator core
+const (
+	BUTTON_A Buttons = 1 << iota
+	BUTTON_B
+	BUTTON_SELECT
+	BUTTON_START
+	BUTTON_RIGHT
+	BUTTON_LEFT
+	BUTTON_UP
+	BUTTON_DOWN
+)
@@ -1,9 +1,423 @@
 package main
 
 import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/seashairo/goboy/internal/disasm"
 	"github.com/seashairo/goboy/internal/goboy"
+	"github.com/seashairo/goboy/internal/ui"
 )
 
 func main() {
-	goboy.Emulate()
+	if len(os.Args) > 1 && os.Args[1] == "test-roms" {
+		os.Exit(testRoms(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "debug" {
+		os.Exit(debug(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "gdb" {
+		os.Exit(gdb(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "disasm" {
+		os.Exit(disassemble(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "cheats" {
+		os.Exit(cheats(os.Args[2:]))
+	}
+
+	options := goboy.DefaultOptions()
+
+	flag.IntVar(&options.Scale, "scale", options.Scale, "screen pixels per Game Boy pixel")
+	flag.StringVar(&options.BootRomPath, "boot-rom", options.BootRomPath, "path to a DMG boot ROM to run before the cartridge")
+	flag.StringVar(&options.SaveDir, "save-dir", options.SaveDir, "directory for battery saves (defaults to the ROM's directory)")
+	flag.BoolVar(&options.Headless, "headless", options.Headless, "run without opening a window or audio device")
+	flag.Float64Var(&options.Speed, "speed", options.Speed, "emulation speed multiplier, 0 for unthrottled")
+	flag.BoolVar(&options.DebugWindows, "debug-windows", options.DebugWindows, "show the tile debug window")
+	flag.BoolVar(&options.Verbose, "verbose", options.Verbose, "print the cartridge header and frame rates")
+	flag.StringVar(&options.SerialOutPath, "serial-out", options.SerialOutPath, "append bytes sent over the serial port to this file")
+	flag.StringVar(&options.SymbolPath, "sym", options.SymbolPath, "RGBDS symbol file (defaults to the .sym file next to the ROM)")
+	flag.StringVar(&options.CheatPath, "cheats", options.CheatPath, "cheat file (defaults to the .cht file next to the ROM)")
+	flag.Func("cheat", "Game Genie or GameShark code to turn on, can be given more than once", func(value string) error {
+		_, err := goboy.ParseCheat(value)
+		options.Cheats = append(options.Cheats, value)
+		return err
+	})
+
+	flag.StringVar(&options.TracePath, "trace", options.TracePath, "write a trace of every instruction run to this file")
+	flag.Func("trace-format", "trace format: doctor, bgb or json (default doctor)", func(value string) error {
+		format, err := goboy.ParseTraceFormat(value)
+		options.Trace.Format = format
+		return err
+	})
+	flag.Func("trace-pc", "only trace instructions with PC in a hex start-end range, e.g. 0150-3FFF", func(value string) error {
+		startText, endText, _ := strings.Cut(value, "-")
+		start, err := strconv.ParseUint(startText, 16, 16)
+		if err != nil {
+			return err
+		}
+		end, err := strconv.ParseUint(endText, 16, 16)
+		options.Trace.StartPC, options.Trace.EndPC = uint16(start), uint16(end)
+		return err
+	})
+	flag.IntVar(&options.Trace.Bank, "trace-bank", options.Trace.Bank, "only trace instructions running from this ROM bank, -1 for any")
+	flag.Uint64Var(&options.Trace.After, "trace-after", options.Trace.After, "skip this many instructions before tracing")
+	flag.IntVar(&options.Trace.RingSize, "trace-ring", options.Trace.RingSize, "only keep the last N instructions, and write them out if the CPU locks up or on exit")
+	flag.BoolVar(&options.Trace.IncludePPU, "trace-ppu", options.Trace.IncludePPU, "include LY and the PPU mode in the trace")
+	flag.BoolVar(&options.Trace.IncludeCycles, "trace-cycles", options.Trace.IncludeCycles, "include the M-cycle count in the trace")
+
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [options] <rom>\n       %s test-roms [options] <dir>\n       %s debug [options] <rom>\n       %s gdb [options] <rom>\n       %s disasm [options] <rom>\n       %s cheats [options] <rom> [command]\n\nOptions:\n", os.Args[0], os.Args[0], os.Args[0], os.Args[0], os.Args[0], os.Args[0])
+		flag.PrintDefaults()
+	}
+
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	// The other trace options don't do anything without somewhere to write to
+	if options.TracePath == "" {
+		flag.Visit(func(f *flag.Flag) {
+			if strings.HasPrefix(f.Name, "trace-") {
+				fmt.Fprintf(os.Stderr, "goboy: -%s needs -trace\n", f.Name)
+				os.Exit(2)
+			}
+		})
+	}
+
+	options.RomPath = flag.Arg(0)
+
+	emulate := ui.Emulate
+	if options.Headless {
+		emulate = goboy.EmulateHeadless
+	}
+
+	if err := emulate(options); err != nil {
+		fmt.Fprintf(os.Stderr, "goboy: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// Runs every test ROM in a directory and prints a table of the results. Exits
+// with a failure if any of them didn't pass.
+func testRoms(args []string) int {
+	flags := flag.NewFlagSet("test-roms", flag.ExitOnError)
+	frames := flags.Int("frames", goboy.DEFAULT_TEST_ROM_FRAME_BUDGET, "frames to run each ROM for before giving up")
+
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: %s test-roms [options] <dir>\n\nOptions:\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+
+	reports, err := goboy.RunTestRoms(flags.Arg(0), *frames)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "goboy: %v\n", err)
+		return 1
+	}
+
+	goboy.PrintTestRomReports(os.Stdout, reports)
+
+	for _, report := range reports {
+		if report.Result != goboy.TEST_ROM_PASSED {
+			return 1
+		}
+	}
+
+	return 0
+}
+
+// Runs a ROM under the command line debugger, reading commands from stdin.
+// Ctrl-C stops whatever the debugger is running rather than exiting.
+func debug(args []string) int {
+	options := goboy.DefaultOptions()
+	options.Headless = true
+	options.Speed = 0
+
+	flags := flag.NewFlagSet("debug", flag.ExitOnError)
+	flags.StringVar(&options.BootRomPath, "boot-rom", options.BootRomPath, "path to a DMG boot ROM to run before the cartridge")
+	flags.StringVar(&options.SaveDir, "save-dir", options.SaveDir, "directory for battery saves (defaults to the ROM's directory)")
+	flags.StringVar(&options.SymbolPath, "sym", options.SymbolPath, "RGBDS symbol file (defaults to the .sym file next to the ROM)")
+
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: %s debug [options] <rom>\n\nOptions:\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+
+	options.RomPath = flags.Arg(0)
+
+	gameboy, err := goboy.NewGameBoy(options)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "goboy: %v\n", err)
+		return 1
+	}
+
+	debugger := goboy.NewDebugger(gameboy, os.Stdout)
+
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, os.Interrupt)
+	go func() {
+		for range interrupts {
+			debugger.Interrupt()
+		}
+	}()
+
+	debugger.Run(os.Stdin)
+
+	if err := debugger.Close(); err != nil {
+		fmt.Fprintf(os.Stderr, "goboy: %v\n", err)
+		return 1
+	}
+
+	return 0
+}
+
+// Prints a listing of some or all of a ROM's banks
+func disassemble(args []string) int {
+	flags := flag.NewFlagSet("disasm", flag.ExitOnError)
+	banks := flags.String("banks", "", "bank, or first-last range of banks, to list (defaults to all of them)")
+	symbolPath := flags.String("sym", "", "RGBDS symbol file (defaults to the .sym file next to the ROM)")
+
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: %s disasm [options] <rom>\n\nOptions:\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+
+	rom, err := os.ReadFile(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "goboy: %v\n", err)
+		return 1
+	}
+
+	symbols, err := goboy.LoadSymbolsFor(flags.Arg(0), *symbolPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "goboy: %v\n", err)
+		return 1
+	}
+
+	first, last := 0, len(rom)/disasm.ROM_BANK_SIZE-1
+	if *banks != "" {
+		firstText, lastText, isRange := strings.Cut(*banks, "-")
+		if !isRange {
+			lastText = firstText
+		}
+
+		first, err = strconv.Atoi(firstText)
+		if err == nil {
+			last, err = strconv.Atoi(lastText)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "goboy: invalid banks %q\n", *banks)
+			return 2
+		}
+	}
+
+	out := bufio.NewWriter(os.Stdout)
+	err = disasm.WriteListing(out, rom, first, last, symbols)
+	if err == nil {
+		err = out.Flush()
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "goboy: %v\n", err)
+		return 1
+	}
+
+	return 0
+}
+
+// Serves a ROM to GDB over the remote serial protocol. Ctrl-C stops accepting
+// connections, and exits once the current one is closed.
+func gdb(args []string) int {
+	options := goboy.DefaultOptions()
+	options.Headless = true
+	options.Speed = 0
+
+	flags := flag.NewFlagSet("gdb", flag.ExitOnError)
+	port := flags.Int("port", 2345, "local TCP port to listen on")
+	flags.StringVar(&options.BootRomPath, "boot-rom", options.BootRomPath, "path to a DMG boot ROM to run before the cartridge")
+	flags.StringVar(&options.SaveDir, "save-dir", options.SaveDir, "directory for battery saves (defaults to the ROM's directory)")
+	flags.StringVar(&options.SymbolPath, "sym", options.SymbolPath, "RGBDS symbol file (defaults to the .sym file next to the ROM)")
+
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: %s gdb [options] <rom>\n\nOptions:\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+
+	options.RomPath = flags.Arg(0)
+
+	gameboy, err := goboy.NewGameBoy(options)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "goboy: %v\n", err)
+		return 1
+	}
+
+	server, err := goboy.NewGdbServer(gameboy, fmt.Sprintf("localhost:%d", *port))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "goboy: %v\n", err)
+		return 1
+	}
+
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, os.Interrupt)
+	go func() {
+		<-interrupts
+		server.Close()
+	}()
+
+	fmt.Printf("Waiting for GDB on %s\n", server.Addr())
+
+	if err := server.Serve(); err != nil {
+		fmt.Fprintf(os.Stderr, "goboy: %v\n", err)
+		return 1
+	}
+
+	if err := gameboy.Close(); err != nil {
+		fmt.Fprintf(os.Stderr, "goboy: %v\n", err)
+		return 1
+	}
+
+	return 0
+}
+
+// Lists or edits the cheats in a ROM's cheat file
+func cheats(args []string) int {
+	flags := flag.NewFlagSet("cheats", flag.ExitOnError)
+	cheatPath := flags.String("file", "", "cheat file (defaults to the .cht file next to the ROM)")
+
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: %s cheats [options] <rom> [command]\n\nCommands:\n", os.Args[0])
+		fmt.Fprintln(flags.Output(), "  list                      list the cheats (default)")
+		fmt.Fprintln(flags.Output(), "  add <code> [description]  add a Game Genie or GameShark code, turned on")
+		fmt.Fprintln(flags.Output(), "  on <n>, off <n>           turn cheat n on or off")
+		fmt.Fprintln(flags.Output(), "  remove <n>                remove cheat n")
+		fmt.Fprintln(flags.Output(), "\nOptions:")
+		flags.PrintDefaults()
+	}
+
+	flags.Parse(args)
+
+	if flags.NArg() < 1 {
+		flags.Usage()
+		return 2
+	}
+
+	if *cheatPath == "" {
+		*cheatPath = goboy.CheatPathFor(flags.Arg(0))
+	}
+
+	list, err := goboy.LoadCheatsFor(flags.Arg(0), *cheatPath)
+	if errors.Is(err, os.ErrNotExist) {
+		list, err = nil, nil
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "goboy: %v\n", err)
+		return 1
+	}
+
+	command := flags.Args()[1:]
+	if len(command) == 0 {
+		command = []string{"list"}
+	}
+
+	index := func() (int, error) {
+		if len(command) != 2 {
+			return 0, fmt.Errorf("%s needs a cheat number", command[0])
+		}
+
+		n, err := strconv.Atoi(command[1])
+		if err != nil || n < 1 || n > len(list) {
+			return 0, fmt.Errorf("no cheat %s", command[1])
+		}
+
+		return n - 1, nil
+	}
+
+	switch command[0] {
+	case "list":
+		if len(list) == 0 {
+			fmt.Println("No cheats")
+		}
+		for i, cheat := range list {
+			state := "off"
+			if cheat.Enabled {
+				state = "on"
+			}
+			fmt.Printf("%d: %-3s %-11s %-10s %-19s %s\n", i+1, state, cheat.Code, cheat.Format, cheat.Effect(), cheat.Description)
+		}
+		return 0
+
+	case "add":
+		if len(command) < 2 {
+			err = errors.New("add needs a code")
+			break
+		}
+
+		var cheat goboy.Cheat
+		if cheat, err = goboy.ParseCheat(command[1]); err == nil {
+			cheat.Description = strings.Join(command[2:], " ")
+			list = append(list, cheat)
+		}
+
+	case "on", "off":
+		var i int
+		if i, err = index(); err == nil {
+			list[i].Enabled = command[0] == "on"
+		}
+
+	case "remove":
+		var i int
+		if i, err = index(); err == nil {
+			list = slices.Delete(list, i, i+1)
+		}
+
+	default:
+		err = fmt.Errorf("unknown command %q", command[0])
+	}
+
+	if err == nil {
+		err = goboy.SaveCheats(*cheatPath, list)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "goboy: %v\n", err)
+		return 1
+	}
+
+	return 0
 }
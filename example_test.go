@@ -0,0 +1,88 @@
+package goboy_test
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/seashairo/goboy"
+)
+
+func Example() {
+	rom, err := os.ReadFile("game.gb")
+	if err != nil {
+		panic(err)
+	}
+
+	gb, err := goboy.New(goboy.Options{})
+	if err != nil {
+		panic(err)
+	}
+
+	if err := gb.LoadROM(rom); err != nil {
+		panic(err)
+	}
+
+	for {
+		gb.SetButtons(goboy.BUTTON_START)
+
+		if err := gb.RunFrame(); err != nil {
+			panic(err)
+		}
+
+		frame := gb.Framebuffer()
+		samples := gb.AudioSamples()
+
+		// Draw the frame and play the samples
+		_, _ = frame, samples
+	}
+}
+
+func ExampleGameBoy_StepInstruction() {
+	rom, _ := os.ReadFile("game.gb")
+
+	gb, _ := goboy.New(goboy.Options{})
+	gb.LoadROM(rom)
+
+	// Trace the first few instructions of the cartridge
+	for i := 0; i < 10; i++ {
+		registers := gb.Registers()
+		opcode := gb.ReadMemory(registers.PC)
+
+		cycles, err := gb.StepInstruction()
+		if err != nil {
+			panic(err)
+		}
+
+		fmt.Printf("PC:%4.4X opcode:%2.2X cycles:%d\n", registers.PC, opcode, cycles)
+	}
+}
+
+func ExampleGameBoy_AddMemoryHook() {
+	rom, _ := os.ReadFile("game.gb")
+
+	gb, _ := goboy.New(goboy.Options{})
+	gb.LoadROM(rom)
+
+	// Report whenever the game changes a byte in work RAM, e.g. to spot where
+	// the player's lives are kept
+	gb.AddMemoryHook(goboy.MemoryHook{
+		Kind:  goboy.HOOK_WRITE,
+		Start: 0xC000,
+		End:   0xDFFF,
+		Bank:  -1,
+		Callback: func(access goboy.MemoryAccess) {
+			fmt.Printf("%4.4X = %2.2X\n", access.Address, access.Value)
+		},
+	})
+
+	gb.RunFrame()
+}
+
+func ExampleFrame_Image() {
+	gb, _ := goboy.New(goboy.Options{})
+
+	// The frame converts to a standard image for saving or scaling
+	img := gb.Framebuffer().Image()
+	fmt.Println(img.Bounds())
+	// Output: (0,0)-(160,144)
+}
@@ -0,0 +1,38 @@
+package goboy
+
+import (
+	"image"
+	"image/color"
+
+	"github.com/seashairo/goboy/internal/goboy"
+)
+
+const (
+	SCREEN_WIDTH  = goboy.LCD_WIDTH
+	SCREEN_HEIGHT = goboy.LCD_HEIGHT
+)
+
+// The screen as 0xAARRGGBB pixels, row by row from the top left
+type Frame [SCREEN_WIDTH * SCREEN_HEIGHT]uint32
+
+func (frame *Frame) At(x int, y int) uint32 {
+	return frame[y*SCREEN_WIDTH+x]
+}
+
+func (frame *Frame) Image() *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, SCREEN_WIDTH, SCREEN_HEIGHT))
+
+	for y := 0; y < SCREEN_HEIGHT; y++ {
+		for x := 0; x < SCREEN_WIDTH; x++ {
+			pixel := frame.At(x, y)
+			img.SetRGBA(x, y, color.RGBA{
+				R: byte(pixel >> 16),
+				G: byte(pixel >> 8),
+				B: byte(pixel),
+				A: byte(pixel >> 24),
+			})
+		}
+	}
+
+	return img
+}
@@ -0,0 +1,224 @@
+// Package goboy is an embeddable Game Boy (DMG) emulator.
+//
+// A GameBoy is driven entirely by the caller: load a ROM, then call RunFrame
+// (or StepInstruction) in a loop, drawing the Framebuffer and playing the
+// AudioSamples after each frame. None of the methods are safe to call from
+// more than one goroutine at a time.
+package goboy
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/seashairo/goboy/internal/goboy"
+)
+
+// Audio samples are signed 16 bit stereo at this rate
+const AUDIO_SAMPLE_RATE = goboy.SAMPLE_RATE
+
+var ErrNoROM = errors.New("no ROM loaded")
+
+// Memory hooks are called for the reads and writes the game makes, and the
+// instructions it runs, in a range of addresses. See AddMemoryHook.
+type (
+	MemoryHook     = goboy.MemoryHook
+	MemoryHookKind = goboy.MemoryHookKind
+	MemoryHookId   = goboy.MemoryHookId
+	MemoryAccess   = goboy.MemoryAccess
+)
+
+const (
+	HOOK_READ    = goboy.HOOK_READ
+	HOOK_WRITE   = goboy.HOOK_WRITE
+	HOOK_EXECUTE = goboy.HOOK_EXECUTE
+)
+
+type Options struct {
+	// A 256 byte DMG boot ROM to run before the cartridge. Optional.
+	BootROM []byte
+}
+
+type GameBoy struct {
+	options Options
+	rom     []byte
+	gameboy *goboy.GameBoy
+	samples []int16
+}
+
+// A snapshot of the CPU registers
+type Registers struct {
+	A, F, B, C, D, E, H, L byte
+	SP, PC                 uint16
+}
+
+func New(options Options) (*GameBoy, error) {
+	if options.BootROM != nil && len(options.BootROM) != 0x100 {
+		return nil, fmt.Errorf("boot ROM is %d bytes, expected 256", len(options.BootROM))
+	}
+
+	return &GameBoy{options: options}, nil
+}
+
+// Inserts a cartridge and powers the machine on. Any previously loaded ROM is
+// replaced. Battery backed RAM starts empty and isn't saved anywhere.
+func (gb *GameBoy) LoadROM(rom []byte) error {
+	// The caller decides how often to run frames, so they shouldn't be held
+	// back to the Game Boy's own speed
+	options := goboy.DefaultOptions()
+	options.Speed = 0
+
+	gameboy, err := goboy.NewGameBoyFromRom(options, rom, gb.options.BootROM)
+	if err != nil {
+		return err
+	}
+
+	gameboy.RegisterAudioCallback(func(left int16, right int16) {
+		gb.samples = append(gb.samples, left, right)
+	})
+
+	gb.rom = rom
+	gb.gameboy = gameboy
+	gb.samples = nil
+
+	return nil
+}
+
+// Switches the machine off and on again, keeping the cartridge's RAM
+func (gb *GameBoy) Reset() error {
+	if gb.gameboy == nil {
+		return ErrNoROM
+	}
+
+	gb.gameboy.Reset()
+	gb.samples = nil
+
+	return nil
+}
+
+// Runs until the PPU finishes the current frame, or returns early if the game
+// runs STOP, as nothing is drawn until a button is pressed
+func (gb *GameBoy) RunFrame() error {
+	if gb.gameboy == nil {
+		return ErrNoROM
+	}
+
+	target := gb.gameboy.FrameCount() + 1
+	for gb.gameboy.FrameCount() != target {
+		gb.gameboy.Step()
+
+		if gb.gameboy.InStopMode() {
+			break
+		}
+	}
+
+	return nil
+}
+
+// Runs a single CPU instruction and returns the number of M-cycles it took.
+// While the CPU is halted each step is a single cycle.
+func (gb *GameBoy) StepInstruction() (int, error) {
+	if gb.gameboy == nil {
+		return 0, ErrNoROM
+	}
+
+	return gb.gameboy.Step(), nil
+}
+
+// Returns a copy of the screen. Straight after RunFrame this is the complete
+// frame, after StepInstruction it may be part way through being drawn.
+func (gb *GameBoy) Framebuffer() *Frame {
+	frame := &Frame{}
+
+	if gb.gameboy != nil {
+		copy(frame[:], gb.gameboy.Framebuffer())
+	}
+
+	return frame
+}
+
+// Sets which buttons are held down, from the next instruction onwards. Buttons
+// not included are released.
+func (gb *GameBoy) SetButtons(buttons Buttons) {
+	if gb.gameboy == nil {
+		return
+	}
+
+	for button := goboy.JOYPAD_A; button <= goboy.JOYPAD_DOWN; button++ {
+		if buttons&(1<<button) != 0 {
+			gb.gameboy.Press(button)
+		} else {
+			gb.gameboy.Release(button)
+		}
+	}
+}
+
+// Reads a byte as the CPU would see it, so reading registers with side effects
+// has those side effects. Reads 0xFF without a ROM.
+func (gb *GameBoy) ReadMemory(address uint16) byte {
+	if gb.gameboy == nil {
+		return 0xFF
+	}
+
+	return gb.gameboy.ReadMemory(address)
+}
+
+// Writes a byte as the CPU would, so writes to ROM go to the cartridge's
+// memory bank controller rather than changing the ROM
+func (gb *GameBoy) WriteMemory(address uint16, value byte) {
+	if gb.gameboy == nil {
+		return
+	}
+
+	gb.gameboy.WriteMemory(address, value)
+}
+
+// Calls hook.Callback for matching memory accesses while RunFrame or
+// StepInstruction is running. ReadMemory and WriteMemory don't call hooks.
+// Hooks stay registered through Reset, but loading another ROM removes them.
+func (gb *GameBoy) AddMemoryHook(hook MemoryHook) (MemoryHookId, error) {
+	if gb.gameboy == nil {
+		return 0, ErrNoROM
+	}
+
+	return gb.gameboy.AddMemoryHook(hook), nil
+}
+
+func (gb *GameBoy) RemoveMemoryHook(id MemoryHookId) {
+	if gb.gameboy == nil {
+		return
+	}
+
+	gb.gameboy.RemoveMemoryHook(id)
+}
+
+func (gb *GameBoy) Registers() Registers {
+	if gb.gameboy == nil {
+		return Registers{}
+	}
+
+	read := func(register goboy.CpuRegister) byte {
+		return byte(gb.gameboy.ReadRegister(register))
+	}
+
+	return Registers{
+		A:  read(goboy.R_A),
+		F:  read(goboy.R_F),
+		B:  read(goboy.R_B),
+		C:  read(goboy.R_C),
+		D:  read(goboy.R_D),
+		E:  read(goboy.R_E),
+		H:  read(goboy.R_H),
+		L:  read(goboy.R_L),
+		SP: gb.gameboy.ReadRegister(goboy.R_SP),
+		PC: gb.gameboy.ReadRegister(goboy.R_PC),
+	}
+}
+
+// Returns the interleaved left/right samples generated since the last call, at
+// AUDIO_SAMPLE_RATE
+func (gb *GameBoy) AudioSamples() []int16 {
+	samples := gb.samples
+	gb.samples = nil
+
+	return samples
+}
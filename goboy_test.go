@@ -0,0 +1,148 @@
+package goboy
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+)
+
+const TEST_ROM_PATH = "./data/roms/blargg/cpu_instrs.gb"
+
+func newTestGameBoy(t *testing.T) *GameBoy {
+	t.Helper()
+
+	rom, err := os.ReadFile(TEST_ROM_PATH)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gb, err := New(Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := gb.LoadROM(rom); err != nil {
+		t.Fatal(err)
+	}
+
+	return gb
+}
+
+func TestGameBoy_RequiresROM(t *testing.T) {
+	gb, err := New(Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := gb.RunFrame(); !errors.Is(err, ErrNoROM) {
+		t.Errorf("expected RunFrame to need a ROM, got %v", err)
+	}
+
+	if _, err := gb.StepInstruction(); !errors.Is(err, ErrNoROM) {
+		t.Errorf("expected StepInstruction to need a ROM, got %v", err)
+	}
+
+	if _, err := gb.AddMemoryHook(MemoryHook{}); !errors.Is(err, ErrNoROM) {
+		t.Errorf("expected AddMemoryHook to need a ROM, got %v", err)
+	}
+}
+
+func TestGameBoy_RejectsBadBootROM(t *testing.T) {
+	if _, err := New(Options{BootROM: make([]byte, 100)}); err == nil {
+		t.Error("expected an error for a boot ROM that isn't 256 bytes")
+	}
+}
+
+func TestGameBoy_RunFrame(t *testing.T) {
+	gb := newTestGameBoy(t)
+
+	if registers := gb.Registers(); registers.PC != 0x0100 || registers.SP != 0xFFFE {
+		t.Fatalf("expected the post boot ROM register state, got %+v", registers)
+	}
+
+	for i := 0; i < 5; i++ {
+		if err := gb.RunFrame(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if registers := gb.Registers(); registers.PC == 0x0100 {
+		t.Errorf("expected the CPU to have moved on, got %+v", registers)
+	}
+
+	if bounds := gb.Framebuffer().Image().Bounds(); bounds.Dx() != SCREEN_WIDTH || bounds.Dy() != SCREEN_HEIGHT {
+		t.Errorf("expected a %dx%d image, got %v", SCREEN_WIDTH, SCREEN_HEIGHT, bounds)
+	}
+
+	if samples := gb.AudioSamples(); len(samples) == 0 {
+		t.Error("expected audio samples to have been generated")
+	}
+
+	if samples := gb.AudioSamples(); len(samples) != 0 {
+		t.Errorf("expected samples to only be returned once, got %d more", len(samples))
+	}
+}
+
+func TestGameBoy_RunFrameIsNotThrottled(t *testing.T) {
+	gb := newTestGameBoy(t)
+
+	// Held to 60 fps, this would take a second
+	start := time.Now()
+	for i := 0; i < 60; i++ {
+		if err := gb.RunFrame(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if elapsed := time.Since(start); elapsed > 800*time.Millisecond {
+		t.Errorf("expected frames to run as fast as they're asked for, 60 took %v", elapsed)
+	}
+}
+
+func TestGameBoy_Memory(t *testing.T) {
+	gb := newTestGameBoy(t)
+
+	gb.WriteMemory(0xC123, 0x42)
+	if value := gb.ReadMemory(0xC123); value != 0x42 {
+		t.Errorf("expected to read back 0x42 from work RAM, got 0x%2.2X", value)
+	}
+
+	gb.StepInstruction()
+	gb.Reset()
+
+	if registers := gb.Registers(); registers.PC != 0x0100 {
+		t.Errorf("expected reset to start again from 0x0100, got 0x%4.4X", registers.PC)
+	}
+
+	if value := gb.ReadMemory(0xC123); value != 0x00 {
+		t.Errorf("expected reset to clear work RAM, got 0x%2.2X", value)
+	}
+}
+
+func TestGameBoy_MemoryHooks(t *testing.T) {
+	gb := newTestGameBoy(t)
+
+	executed := 0
+	id, err := gb.AddMemoryHook(MemoryHook{
+		Kind:     HOOK_EXECUTE,
+		Start:    0x0100,
+		End:      0x0100,
+		Bank:     -1,
+		Callback: func(access MemoryAccess) { executed++ },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gb.StepInstruction()
+	gb.Reset()
+	gb.StepInstruction()
+	gb.RemoveMemoryHook(id)
+	gb.Reset()
+	gb.StepInstruction()
+
+	if executed != 2 {
+		t.Errorf("expected the entry point to run twice while hooked, got %d", executed)
+	}
+}
@@ -0,0 +1,435 @@
+// Package disasm decodes SM83 machine code, the Game Boy's CPU instruction
+// set, into assembly text along with how long each instruction takes.
+package disasm
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Mnemonics for each opcode, with placeholders for their operands: n8 and n16
+// are immediate data, a8 and a16 addresses, and e8 a signed offset. Opcodes
+// without a mnemonic lock up the CPU.
+// @see https://gbdev.io/gb-opcodes/optables/
+var MNEMONICS = [0x100]string{
+	0x00: "NOP",
+	0x01: "LD BC, n16",
+	0x02: "LD (BC), A",
+	0x03: "INC BC",
+	0x04: "INC B",
+	0x05: "DEC B",
+	0x06: "LD B, n8",
+	0x07: "RLCA",
+	0x08: "LD (a16), SP",
+	0x09: "ADD HL, BC",
+	0x0A: "LD A, (BC)",
+	0x0B: "DEC BC",
+	0x0C: "INC C",
+	0x0D: "DEC C",
+	0x0E: "LD C, n8",
+	0x0F: "RRCA",
+	0x10: "STOP",
+	0x11: "LD DE, n16",
+	0x12: "LD (DE), A",
+	0x13: "INC DE",
+	0x14: "INC D",
+	0x15: "DEC D",
+	0x16: "LD D, n8",
+	0x17: "RLA",
+	0x18: "JR e8",
+	0x19: "ADD HL, DE",
+	0x1A: "LD A, (DE)",
+	0x1B: "DEC DE",
+	0x1C: "INC E",
+	0x1D: "DEC E",
+	0x1E: "LD E, n8",
+	0x1F: "RRA",
+	0x20: "JR NZ, e8",
+	0x21: "LD HL, n16",
+	0x22: "LD (HL+), A",
+	0x23: "INC HL",
+	0x24: "INC H",
+	0x25: "DEC H",
+	0x26: "LD H, n8",
+	0x27: "DAA",
+	0x28: "JR Z, e8",
+	0x29: "ADD HL, HL",
+	0x2A: "LD A, (HL+)",
+	0x2B: "DEC HL",
+	0x2C: "INC L",
+	0x2D: "DEC L",
+	0x2E: "LD L, n8",
+	0x2F: "CPL",
+	0x30: "JR NC, e8",
+	0x31: "LD SP, n16",
+	0x32: "LD (HL-), A",
+	0x33: "INC SP",
+	0x34: "INC (HL)",
+	0x35: "DEC (HL)",
+	0x36: "LD (HL), n8",
+	0x37: "SCF",
+	0x38: "JR C, e8",
+	0x39: "ADD HL, SP",
+	0x3A: "LD A, (HL-)",
+	0x3B: "DEC SP",
+	0x3C: "INC A",
+	0x3D: "DEC A",
+	0x3E: "LD A, n8",
+	0x3F: "CCF",
+	0x40: "LD B, B",
+	0x41: "LD B, C",
+	0x42: "LD B, D",
+	0x43: "LD B, E",
+	0x44: "LD B, H",
+	0x45: "LD B, L",
+	0x46: "LD B, (HL)",
+	0x47: "LD B, A",
+	0x48: "LD C, B",
+	0x49: "LD C, C",
+	0x4A: "LD C, D",
+	0x4B: "LD C, E",
+	0x4C: "LD C, H",
+	0x4D: "LD C, L",
+	0x4E: "LD C, (HL)",
+	0x4F: "LD C, A",
+	0x50: "LD D, B",
+	0x51: "LD D, C",
+	0x52: "LD D, D",
+	0x53: "LD D, E",
+	0x54: "LD D, H",
+	0x55: "LD D, L",
+	0x56: "LD D, (HL)",
+	0x57: "LD D, A",
+	0x58: "LD E, B",
+	0x59: "LD E, C",
+	0x5A: "LD E, D",
+	0x5B: "LD E, E",
+	0x5C: "LD E, H",
+	0x5D: "LD E, L",
+	0x5E: "LD E, (HL)",
+	0x5F: "LD E, A",
+	0x60: "LD H, B",
+	0x61: "LD H, C",
+	0x62: "LD H, D",
+	0x63: "LD H, E",
+	0x64: "LD H, H",
+	0x65: "LD H, L",
+	0x66: "LD H, (HL)",
+	0x67: "LD H, A",
+	0x68: "LD L, B",
+	0x69: "LD L, C",
+	0x6A: "LD L, D",
+	0x6B: "LD L, E",
+	0x6C: "LD L, H",
+	0x6D: "LD L, L",
+	0x6E: "LD L, (HL)",
+	0x6F: "LD L, A",
+	0x70: "LD (HL), B",
+	0x71: "LD (HL), C",
+	0x72: "LD (HL), D",
+	0x73: "LD (HL), E",
+	0x74: "LD (HL), H",
+	0x75: "LD (HL), L",
+	0x76: "HALT",
+	0x77: "LD (HL), A",
+	0x78: "LD A, B",
+	0x79: "LD A, C",
+	0x7A: "LD A, D",
+	0x7B: "LD A, E",
+	0x7C: "LD A, H",
+	0x7D: "LD A, L",
+	0x7E: "LD A, (HL)",
+	0x7F: "LD A, A",
+	0x80: "ADD A, B",
+	0x81: "ADD A, C",
+	0x82: "ADD A, D",
+	0x83: "ADD A, E",
+	0x84: "ADD A, H",
+	0x85: "ADD A, L",
+	0x86: "ADD A, (HL)",
+	0x87: "ADD A, A",
+	0x88: "ADC A, B",
+	0x89: "ADC A, C",
+	0x8A: "ADC A, D",
+	0x8B: "ADC A, E",
+	0x8C: "ADC A, H",
+	0x8D: "ADC A, L",
+	0x8E: "ADC A, (HL)",
+	0x8F: "ADC A, A",
+	0x90: "SUB B",
+	0x91: "SUB C",
+	0x92: "SUB D",
+	0x93: "SUB E",
+	0x94: "SUB H",
+	0x95: "SUB L",
+	0x96: "SUB (HL)",
+	0x97: "SUB A",
+	0x98: "SBC A, B",
+	0x99: "SBC A, C",
+	0x9A: "SBC A, D",
+	0x9B: "SBC A, E",
+	0x9C: "SBC A, H",
+	0x9D: "SBC A, L",
+	0x9E: "SBC A, (HL)",
+	0x9F: "SBC A, A",
+	0xA0: "AND B",
+	0xA1: "AND C",
+	0xA2: "AND D",
+	0xA3: "AND E",
+	0xA4: "AND H",
+	0xA5: "AND L",
+	0xA6: "AND (HL)",
+	0xA7: "AND A",
+	0xA8: "XOR B",
+	0xA9: "XOR C",
+	0xAA: "XOR D",
+	0xAB: "XOR E",
+	0xAC: "XOR H",
+	0xAD: "XOR L",
+	0xAE: "XOR (HL)",
+	0xAF: "XOR A",
+	0xB0: "OR B",
+	0xB1: "OR C",
+	0xB2: "OR D",
+	0xB3: "OR E",
+	0xB4: "OR H",
+	0xB5: "OR L",
+	0xB6: "OR (HL)",
+	0xB7: "OR A",
+	0xB8: "CP B",
+	0xB9: "CP C",
+	0xBA: "CP D",
+	0xBB: "CP E",
+	0xBC: "CP H",
+	0xBD: "CP L",
+	0xBE: "CP (HL)",
+	0xBF: "CP A",
+	0xC0: "RET NZ",
+	0xC1: "POP BC",
+	0xC2: "JP NZ, a16",
+	0xC3: "JP a16",
+	0xC4: "CALL NZ, a16",
+	0xC5: "PUSH BC",
+	0xC6: "ADD A, n8",
+	0xC7: "RST $00",
+	0xC8: "RET Z",
+	0xC9: "RET",
+	0xCA: "JP Z, a16",
+	0xCB: "PREFIX",
+	0xCC: "CALL Z, a16",
+	0xCD: "CALL a16",
+	0xCE: "ADC A, n8",
+	0xCF: "RST $08",
+	0xD0: "RET NC",
+	0xD1: "POP DE",
+	0xD2: "JP NC, a16",
+	0xD4: "CALL NC, a16",
+	0xD5: "PUSH DE",
+	0xD6: "SUB n8",
+	0xD7: "RST $10",
+	0xD8: "RET C",
+	0xD9: "RETI",
+	0xDA: "JP C, a16",
+	0xDC: "CALL C, a16",
+	0xDE: "SBC A, n8",
+	0xDF: "RST $18",
+	0xE0: "LDH (a8), A",
+	0xE1: "POP HL",
+	0xE2: "LD (C), A",
+	0xE5: "PUSH HL",
+	0xE6: "AND n8",
+	0xE7: "RST $20",
+	0xE8: "ADD SP, e8",
+	0xE9: "JP HL",
+	0xEA: "LD (a16), A",
+	0xEE: "XOR n8",
+	0xEF: "RST $28",
+	0xF0: "LDH A, (a8)",
+	0xF1: "POP AF",
+	0xF2: "LD A, (C)",
+	0xF3: "DI",
+	0xF5: "PUSH AF",
+	0xF6: "OR n8",
+	0xF7: "RST $30",
+	0xF8: "LD HL, SP+e8",
+	0xF9: "LD SP, HL",
+	0xFA: "LD A, (a16)",
+	0xFB: "EI",
+	0xFE: "CP n8",
+	0xFF: "RST $38",
+}
+
+// M-cycles each opcode takes, including fetching it. Conditional jumps, calls
+// and returns take this long when the condition is met, and the matching entry
+// in CYCLES_NOT_TAKEN when it isn't. 0xCB prefixed opcodes are in CB_CYCLES.
+// @see https://gbdev.io/gb-opcodes/optables/
+var CYCLES = [0x100]int{
+	//     1  2  3  4  5  6  7  8  9  A  B  C  D  E  F
+	1, 3, 2, 2, 1, 1, 2, 1, 5, 2, 2, 2, 1, 1, 2, 1, // 0x00
+	1, 3, 2, 2, 1, 1, 2, 1, 3, 2, 2, 2, 1, 1, 2, 1, // 0x10
+	3, 3, 2, 2, 1, 1, 2, 1, 3, 2, 2, 2, 1, 1, 2, 1, // 0x20
+	3, 3, 2, 2, 3, 3, 3, 1, 3, 2, 2, 2, 1, 1, 2, 1, // 0x30
+	1, 1, 1, 1, 1, 1, 2, 1, 1, 1, 1, 1, 1, 1, 2, 1, // 0x40
+	1, 1, 1, 1, 1, 1, 2, 1, 1, 1, 1, 1, 1, 1, 2, 1, // 0x50
+	1, 1, 1, 1, 1, 1, 2, 1, 1, 1, 1, 1, 1, 1, 2, 1, // 0x60
+	2, 2, 2, 2, 2, 2, 1, 2, 1, 1, 1, 1, 1, 1, 2, 1, // 0x70
+	1, 1, 1, 1, 1, 1, 2, 1, 1, 1, 1, 1, 1, 1, 2, 1, // 0x80
+	1, 1, 1, 1, 1, 1, 2, 1, 1, 1, 1, 1, 1, 1, 2, 1, // 0x90
+	1, 1, 1, 1, 1, 1, 2, 1, 1, 1, 1, 1, 1, 1, 2, 1, // 0xA0
+	1, 1, 1, 1, 1, 1, 2, 1, 1, 1, 1, 1, 1, 1, 2, 1, // 0xB0
+	5, 3, 4, 4, 6, 4, 2, 4, 5, 4, 4, 1, 6, 6, 2, 4, // 0xC0
+	5, 3, 4, 0, 6, 4, 2, 4, 5, 4, 4, 0, 6, 0, 2, 4, // 0xD0
+	3, 3, 2, 0, 0, 4, 2, 4, 4, 1, 4, 0, 0, 0, 2, 4, // 0xE0
+	3, 3, 2, 1, 0, 4, 2, 4, 3, 2, 4, 1, 0, 0, 2, 4, // 0xF0
+}
+
+var CYCLES_NOT_TAKEN = map[byte]int{
+	0x20: 2, 0x28: 2, 0x30: 2, 0x38: 2, // JR cc
+	0xC0: 2, 0xC8: 2, 0xD0: 2, 0xD8: 2, // RET cc
+	0xC2: 3, 0xCA: 3, 0xD2: 3, 0xDA: 3, // JP cc
+	0xC4: 3, 0xCC: 3, 0xD4: 3, 0xDC: 3, // CALL cc
+}
+
+// 0xCB prefixed opcodes are split into the operation, the bit it works on (if
+// any) and the register
+// @see https://gbdev.io/pandocs/CPU_Instruction_Set.html#cb-prefix-instructions
+var CB_SHIFT_MNEMONICS = [8]string{"RLC", "RRC", "RL", "RR", "SLA", "SRA", "SWAP", "SRL"}
+var CB_BIT_MNEMONICS = [4]string{"", "BIT", "RES", "SET"}
+var CB_REGISTER_NAMES = [8]string{"B", "C", "D", "E", "H", "L", "(HL)", "A"}
+
+// 0xCB prefixed opcodes take 2 M-cycles, or 4 when they read, modify and write
+// back (HL). BIT only reads (HL), so takes 3.
+func cbCycles(opcode byte) int {
+	switch {
+	case opcode&0x07 != 6:
+		return 2
+	case opcode>>6 == 1:
+		return 3
+	default:
+		return 4
+	}
+}
+
+// A single decoded instruction
+type Instruction struct {
+	Address uint16
+	Bytes   []byte
+	// e.g. "LD"
+	Mnemonic string
+	// e.g. "A, ($FF44)", or empty if the instruction doesn't have any
+	Operands string
+	// M-cycles the instruction takes. For conditional jumps, calls and returns
+	// Cycles is when the condition is met, otherwise both are the same.
+	Cycles         int
+	CyclesNotTaken int
+	// Where a jump, call or RST goes, if it's known without running it
+	Target    uint16
+	HasTarget bool
+	// Invalid opcodes lock up the CPU. They're shown as a single byte of data.
+	Invalid bool
+}
+
+func (instruction Instruction) Length() int {
+	return len(instruction.Bytes)
+}
+
+// True for CALL and RST, which push a return address
+func (instruction Instruction) IsCall() bool {
+	return instruction.Mnemonic == "CALL" || instruction.Mnemonic == "RST"
+}
+
+func (instruction Instruction) String() string {
+	if instruction.Operands == "" {
+		return instruction.Mnemonic
+	}
+
+	return instruction.Mnemonic + " " + instruction.Operands
+}
+
+// Decodes the instruction at an address, reading its bytes with read
+func Disassemble(read func(address uint16) byte, address uint16) Instruction {
+	opcode := read(address)
+	instruction := Instruction{
+		Address:        address,
+		Cycles:         CYCLES[opcode],
+		CyclesNotTaken: CYCLES[opcode],
+	}
+	if cycles, ok := CYCLES_NOT_TAKEN[opcode]; ok {
+		instruction.CyclesNotTaken = cycles
+	}
+
+	text, length := decode(read, address, &instruction)
+	for i := 0; i < length; i++ {
+		instruction.Bytes = append(instruction.Bytes, read(address+uint16(i)))
+	}
+
+	instruction.Mnemonic, instruction.Operands, _ = strings.Cut(text, " ")
+
+	return instruction
+}
+
+func decode(read func(address uint16) byte, address uint16, instruction *Instruction) (string, int) {
+	opcode := read(address)
+
+	switch opcode {
+	case 0xCB:
+		cbOpcode := read(address + 1)
+		instruction.Cycles = cbCycles(cbOpcode)
+		instruction.CyclesNotTaken = instruction.Cycles
+		return disassembleCB(cbOpcode), 2
+	case 0x10:
+		// STOP is followed by a byte which is ignored
+		return "STOP", 2
+	}
+
+	mnemonic := MNEMONICS[opcode]
+
+	switch {
+	case mnemonic == "":
+		instruction.Invalid = true
+		return fmt.Sprintf("DB $%2.2X", opcode), 1
+
+	case strings.Contains(mnemonic, "16"):
+		value := uint16(read(address+2))<<8 | uint16(read(address+1))
+		if strings.HasPrefix(mnemonic, "JP") || strings.HasPrefix(mnemonic, "CALL") {
+			instruction.Target, instruction.HasTarget = value, true
+		}
+		mnemonic = strings.NewReplacer("n16", "$%4.4X", "a16", "$%4.4X").Replace(mnemonic)
+		return fmt.Sprintf(mnemonic, value), 3
+
+	case strings.Contains(mnemonic, "n8"):
+		return strings.Replace(mnemonic, "n8", fmt.Sprintf("$%2.2X", read(address+1)), 1), 2
+
+	case strings.Contains(mnemonic, "a8"):
+		return strings.Replace(mnemonic, "a8", fmt.Sprintf("$FF%2.2X", read(address+1)), 1), 2
+
+	case strings.HasPrefix(mnemonic, "JR"):
+		// Jumps are relative to the end of the instruction
+		target := address + 2 + uint16(int8(read(address+1)))
+		instruction.Target, instruction.HasTarget = target, true
+		return strings.Replace(mnemonic, "e8", fmt.Sprintf("$%4.4X", target), 1), 2
+
+	case strings.Contains(mnemonic, "+e8"):
+		return strings.Replace(mnemonic, "+e8", fmt.Sprintf("%+d", int8(read(address+1))), 1), 2
+
+	case strings.Contains(mnemonic, "e8"):
+		return strings.Replace(mnemonic, "e8", fmt.Sprintf("%d", int8(read(address+1))), 1), 2
+
+	case strings.HasPrefix(mnemonic, "RST"):
+		instruction.Target, instruction.HasTarget = uint16(opcode&0x38), true
+	}
+
+	return mnemonic, 1
+}
+
+func disassembleCB(opcode byte) string {
+	register := CB_REGISTER_NAMES[opcode&0x07]
+	bit := (opcode >> 3) & 0x07
+	operation := opcode >> 6
+
+	if operation == 0 {
+		return fmt.Sprintf("%s %s", CB_SHIFT_MNEMONICS[bit], register)
+	}
+
+	return fmt.Sprintf("%s %d, %s", CB_BIT_MNEMONICS[operation], bit, register)
+}
@@ -0,0 +1,95 @@
+package disasm
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDisassemble(t *testing.T) {
+	for _, test := range []struct {
+		bytes    []byte
+		text     string
+		length   int
+		location uint16
+	}{
+		{[]byte{0x00}, "NOP", 1, 0x0000},
+		{[]byte{0x01, 0x34, 0x12}, "LD BC, $1234", 3, 0x0000},
+		{[]byte{0xE0, 0x44}, "LDH ($FF44), A", 2, 0x0000},
+		{[]byte{0x20, 0xFE}, "JR NZ, $0150", 2, 0x0150},
+		{[]byte{0xF8, 0xFF}, "LD HL, SP-1", 2, 0x0000},
+		{[]byte{0xE8, 0x02}, "ADD SP, 2", 2, 0x0000},
+		{[]byte{0xCB, 0x7C}, "BIT 7, H", 2, 0x0000},
+		{[]byte{0xCB, 0x36}, "SWAP (HL)", 2, 0x0000},
+		{[]byte{0x10, 0x00}, "STOP", 2, 0x0000},
+		{[]byte{0xD3}, "DB $D3", 1, 0x0000},
+	} {
+		read := func(address uint16) byte {
+			return test.bytes[address-test.location]
+		}
+
+		instruction := Disassemble(read, test.location)
+		text, length := instruction.String(), instruction.Length()
+		if text != test.text || length != test.length {
+			t.Errorf("% X: expected %q (%d bytes), got %q (%d bytes)", test.bytes, test.text, test.length, text, length)
+		}
+	}
+}
+
+func TestDisassemble_Cycles(t *testing.T) {
+	for _, test := range []struct {
+		bytes          []byte
+		cycles         int
+		cyclesNotTaken int
+	}{
+		{[]byte{0x00}, 1, 1},
+		{[]byte{0x08, 0x00, 0xC0}, 5, 5},
+		{[]byte{0x20, 0x00}, 3, 2},
+		{[]byte{0xC4, 0x00, 0x00}, 6, 3},
+		{[]byte{0xD8}, 5, 2},
+		{[]byte{0xCB, 0x11}, 2, 2},
+		{[]byte{0xCB, 0x46}, 3, 3},
+		{[]byte{0xCB, 0xC6}, 4, 4},
+	} {
+		instruction := Disassemble(func(address uint16) byte { return test.bytes[address] }, 0)
+		if instruction.Cycles != test.cycles || instruction.CyclesNotTaken != test.cyclesNotTaken {
+			t.Errorf("% X: expected %d/%d M-cycles, got %d/%d", test.bytes, test.cycles, test.cyclesNotTaken, instruction.Cycles, instruction.CyclesNotTaken)
+		}
+	}
+
+	// Every valid opcode takes some time
+	for opcode := range 0x100 {
+		if MNEMONICS[opcode] != "" && CYCLES[opcode] == 0 {
+			t.Errorf("0x%2.2X: expected a cycle count for %s", opcode, MNEMONICS[opcode])
+		}
+		if MNEMONICS[opcode] == "" && opcode != 0xCB && CYCLES[opcode] != 0 {
+			t.Errorf("0x%2.2X: expected no cycle count for an invalid opcode", opcode)
+		}
+	}
+}
+
+func TestWriteListing_LabelsTargets(t *testing.T) {
+	rom := make([]byte, 2*ROM_BANK_SIZE)
+	// 0x0000: JP 0x0004; 0x0003: NOP; 0x0004: CALL 0x4000
+	copy(rom, []byte{0xC3, 0x04, 0x00, 0x00, 0xCD, 0x00, 0x40})
+	// 0x4000: JR -2, which jumps to itself
+	copy(rom[ROM_BANK_SIZE:], []byte{0x18, 0xFE})
+
+	out := &strings.Builder{}
+	if err := WriteListing(out, rom, 0, 1, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, expected := range []string{
+		"    00:0000  C3 04 00  JP L00_0004              ; 4\n",
+		"L00_0004:\n    00:0004  CD 00 40  CALL $4000               ; 6\n",
+		"; Bank 1\nL01_4000:\n    01:4000  18 FE     JR L01_4000              ; 3\n",
+	} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("expected listing to contain %q", expected)
+		}
+	}
+
+	if err := WriteListing(out, rom, 1, 2, nil); err == nil {
+		t.Errorf("expected an error for a bank past the end of the ROM")
+	}
+}
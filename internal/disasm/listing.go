@@ -0,0 +1,136 @@
+package disasm
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+const ROM_BANK_SIZE = 0x4000
+
+type location struct {
+	bank    int
+	address uint16
+}
+
+// Writes a listing of ROM banks first to last from a ROM image, with the
+// M-cycles each instruction takes. Jumps and calls to somewhere in the listing
+// get a label there, to make the control flow easier to follow, named after the
+// symbol there if there is one. There's no way to tell code from data, so
+// everything is decoded as if it's code.
+func WriteListing(out io.Writer, rom []byte, first int, last int, symbols *Symbols) error {
+	bankCount := len(rom) / ROM_BANK_SIZE
+	if first < 0 || last < first || last >= bankCount {
+		return fmt.Errorf("banks %d to %d aren't in a ROM with %d banks", first, last, bankCount)
+	}
+
+	instructions := [][]Instruction{}
+	for bank := first; bank <= last; bank++ {
+		instructions = append(instructions, disassembleBank(rom, bank))
+	}
+
+	// Only label places that are in the listing
+	labels := map[location]string{}
+	for i, bankInstructions := range instructions {
+		for _, instruction := range bankInstructions {
+			target, ok := targetOf(instruction, first+i)
+			if ok && target.bank >= first && target.bank <= last {
+				labels[target] = fmt.Sprintf("L%2.2X_%4.4X", target.bank, target.address)
+			}
+		}
+	}
+
+	for i, bankInstructions := range instructions {
+		for _, instruction := range bankInstructions {
+			if name, ok := symbols.At(first+i, instruction.Address); ok {
+				labels[location{first + i, instruction.Address}] = name
+			}
+		}
+	}
+
+	for i, bankInstructions := range instructions {
+		bank := first + i
+		fmt.Fprintf(out, "; Bank %d\n", bank)
+
+		for _, instruction := range bankInstructions {
+			if label, ok := labels[location{bank, instruction.Address}]; ok {
+				fmt.Fprintf(out, "%s:\n", label)
+			}
+
+			text := symbols.Annotate(instruction, bank)
+			if target, ok := targetOf(instruction, bank); ok && labels[target] != "" {
+				text = strings.Replace(text, fmt.Sprintf("$%4.4X", target.address), labels[target], 1)
+			}
+
+			cycles := fmt.Sprintf("%d", instruction.Cycles)
+			if instruction.CyclesNotTaken != instruction.Cycles {
+				cycles = fmt.Sprintf("%d/%d", instruction.Cycles, instruction.CyclesNotTaken)
+			}
+			if instruction.Invalid {
+				cycles = "-"
+			}
+
+			bytes := make([]string, len(instruction.Bytes))
+			for j, value := range instruction.Bytes {
+				bytes[j] = fmt.Sprintf("%2.2X", value)
+			}
+
+			_, err := fmt.Fprintf(
+				out,
+				"    %2.2X:%4.4X  %-8s  %-24s ; %s\n",
+				bank, instruction.Address, strings.Join(bytes, " "), text, cycles,
+			)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Bank 0 is always mapped at 0x0000, and every other bank is switched in at
+// 0x4000
+func disassembleBank(rom []byte, bank int) []Instruction {
+	start := uint16(0)
+	if bank > 0 {
+		start = ROM_BANK_SIZE
+	}
+	offset := bank * ROM_BANK_SIZE
+
+	read := func(address uint16) byte {
+		index := offset + int(address-start)
+		// Instructions at the end of the bank can run off it
+		if index >= offset+ROM_BANK_SIZE {
+			return 0x00
+		}
+		return rom[index]
+	}
+
+	instructions := []Instruction{}
+	for address := int(start); address < int(start)+ROM_BANK_SIZE; {
+		instruction := Disassemble(read, uint16(address))
+		instructions = append(instructions, instruction)
+		address += instruction.Length()
+	}
+
+	return instructions
+}
+
+// Works out which bank a jump or call from the given bank ends up in. Outside
+// of ROM the bank is -1. Jumps from bank 0 into the switchable bank could end
+// up anywhere, so they don't have a target.
+func targetOf(instruction Instruction, bank int) (location, bool) {
+	switch {
+	case !instruction.HasTarget:
+		return location{}, false
+	case instruction.Target < ROM_BANK_SIZE:
+		return location{0, instruction.Target}, true
+	case instruction.Target >= 2*ROM_BANK_SIZE:
+		return location{-1, instruction.Target}, true
+	case bank > 0:
+		return location{bank, instruction.Target}, true
+	}
+
+	return location{}, false
+}
@@ -0,0 +1,184 @@
+package disasm
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// A label from a symbol file, and where it is
+type Symbol struct {
+	Name    string
+	Bank    int
+	Address uint16
+}
+
+// Labels loaded from an RGBDS symbol file, which has a "bank:address label"
+// line for each one. A nil *Symbols is empty, so it can be used without
+// checking whether a file was loaded.
+// @see https://rgbds.gbdev.io/docs/rgblink.1#n
+type Symbols struct {
+	// Sorted by address, then bank
+	symbols []Symbol
+	byName  map[string]Symbol
+}
+
+func LoadSymbols(path string) (*Symbols, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load symbols: %w", err)
+	}
+	defer file.Close()
+
+	return ParseSymbols(file)
+}
+
+func ParseSymbols(reader io.Reader) (*Symbols, error) {
+	symbols := &Symbols{byName: map[string]Symbol{}}
+
+	scanner := bufio.NewScanner(reader)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line, _, _ := strings.Cut(scanner.Text(), ";")
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		symbol, err := parseSymbol(fields)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load symbols: line %d: %w", lineNumber, err)
+		}
+
+		symbols.symbols = append(symbols.symbols, symbol)
+		symbols.byName[symbol.Name] = symbol
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to load symbols: %w", err)
+	}
+
+	sort.SliceStable(symbols.symbols, func(i, j int) bool {
+		a, b := symbols.symbols[i], symbols.symbols[j]
+		if a.Address != b.Address {
+			return a.Address < b.Address
+		}
+		return a.Bank < b.Bank
+	})
+
+	return symbols, nil
+}
+
+func parseSymbol(fields []string) (Symbol, error) {
+	if len(fields) != 2 {
+		return Symbol{}, fmt.Errorf("expected \"bank:address label\", got %q", strings.Join(fields, " "))
+	}
+
+	bankText, addressText, found := strings.Cut(fields[0], ":")
+	if !found {
+		return Symbol{}, fmt.Errorf("expected bank:address, got %q", fields[0])
+	}
+
+	bank, err := strconv.ParseUint(bankText, 16, 16)
+	if err != nil {
+		return Symbol{}, fmt.Errorf("invalid bank %q", bankText)
+	}
+
+	address, err := strconv.ParseUint(addressText, 16, 16)
+	if err != nil {
+		return Symbol{}, fmt.Errorf("invalid address %q", addressText)
+	}
+
+	return Symbol{Name: fields[1], Bank: int(bank), Address: uint16(address)}, nil
+}
+
+func (symbols *Symbols) Lookup(name string) (Symbol, bool) {
+	if symbols == nil {
+		return Symbol{}, false
+	}
+
+	symbol, ok := symbols.byName[name]
+	return symbol, ok
+}
+
+// Returns the label at an address, if there is one. The bank only matters for
+// ROM, where it's the bank mapped at the address.
+func (symbols *Symbols) At(bank int, address uint16) (string, bool) {
+	if symbols == nil {
+		return "", false
+	}
+
+	i := sort.Search(len(symbols.symbols), func(i int) bool {
+		return symbols.symbols[i].Address >= address
+	})
+
+	for ; i < len(symbols.symbols) && symbols.symbols[i].Address == address; i++ {
+		if symbols.symbols[i].matches(bank, address) {
+			return symbols.symbols[i].Name, true
+		}
+	}
+
+	return "", false
+}
+
+// Names an address after the closest label at or before it, e.g. "main+3".
+// Only labels in the same bank and area of memory count. Returns an empty
+// string if there aren't any.
+func (symbols *Symbols) Nearest(bank int, address uint16) string {
+	if symbols == nil {
+		return ""
+	}
+
+	i := sort.Search(len(symbols.symbols), func(i int) bool {
+		return symbols.symbols[i].Address > address
+	})
+
+	for i--; i >= 0; i-- {
+		symbol := symbols.symbols[i]
+		if !symbol.matches(bank, address) || memoryArea(symbol.Address) != memoryArea(address) {
+			continue
+		}
+
+		if symbol.Address == address {
+			return symbol.Name
+		}
+
+		return fmt.Sprintf("%s+%d", symbol.Name, address-symbol.Address)
+	}
+
+	return ""
+}
+
+func (symbol Symbol) matches(bank int, address uint16) bool {
+	return address >= 2*ROM_BANK_SIZE || bank < 0 || symbol.Bank == bank
+}
+
+// Splits memory up so labels in one area don't name addresses in the next,
+// e.g. the end of WRAM isn't named after the last label in SRAM
+func memoryArea(address uint16) uint16 {
+	if address < 2*ROM_BANK_SIZE {
+		return address / ROM_BANK_SIZE
+	}
+
+	return address / 0x2000
+}
+
+// Replaces the address an instruction jumps or calls to with its label, if it
+// has one. The bank is the one the instruction itself is running from.
+func (symbols *Symbols) Annotate(instruction Instruction, bank int) string {
+	text := instruction.String()
+
+	target, ok := targetOf(instruction, bank)
+	if !ok {
+		return text
+	}
+
+	if name, ok := symbols.At(target.bank, target.address); ok {
+		return strings.Replace(text, fmt.Sprintf("$%4.4X", target.address), name, 1)
+	}
+
+	return text
+}
@@ -0,0 +1,105 @@
+package disasm
+
+import (
+	"strings"
+	"testing"
+)
+
+const TEST_SYMBOLS = `; File generated by rgblink
+00:0150 Main
+00:0158 Main.loop
+01:4000 Bank1Code
+02:4000 Bank2Code
+00:c000 wBuffer
+00:ff80 hDMARoutine
+`
+
+func TestSymbols_Lookup(t *testing.T) {
+	symbols, err := ParseSymbols(strings.NewReader(TEST_SYMBOLS))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if symbol, ok := symbols.Lookup("Main.loop"); !ok || symbol != (Symbol{Name: "Main.loop", Bank: 0, Address: 0x0158}) {
+		t.Errorf("expected Main.loop at 00:0158, got %+v", symbol)
+	}
+	if _, ok := symbols.Lookup("Nope"); ok {
+		t.Errorf("expected no symbol called Nope")
+	}
+
+	if name, ok := symbols.At(2, 0x4000); !ok || name != "Bank2Code" {
+		t.Errorf("expected the label for the mapped bank, got %q", name)
+	}
+	if name, ok := symbols.At(-1, 0xC000); !ok || name != "wBuffer" {
+		t.Errorf("expected RAM labels to ignore the bank, got %q", name)
+	}
+}
+
+func TestSymbols_Nearest(t *testing.T) {
+	symbols, err := ParseSymbols(strings.NewReader(TEST_SYMBOLS))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		bank     int
+		address  uint16
+		expected string
+	}{
+		{0, 0x0150, "Main"},
+		{0, 0x0153, "Main+3"},
+		{0, 0x015A, "Main.loop+2"},
+		{0, 0x0100, ""},
+		{1, 0x4010, "Bank1Code+16"},
+		{3, 0x4010, ""},
+		// Nothing from bank 0 names the switchable bank
+		{3, 0x7000, ""},
+		{-1, 0xC123, "wBuffer+291"},
+		{-1, 0xE000, ""},
+		{-1, 0xFF81, "hDMARoutine+1"},
+	} {
+		if name := symbols.Nearest(test.bank, test.address); name != test.expected {
+			t.Errorf("%2.2X:%4.4X: expected %q, got %q", test.bank, test.address, test.expected, name)
+		}
+	}
+
+	var empty *Symbols
+	if name := empty.Nearest(0, 0x0150); name != "" {
+		t.Errorf("expected no symbols to name nothing, got %q", name)
+	}
+}
+
+func TestSymbols_Errors(t *testing.T) {
+	for _, text := range []string{"0150 Main", "zz:0150 Main", "00:0150", "00:10000 Main"} {
+		if _, err := ParseSymbols(strings.NewReader(text)); err == nil {
+			t.Errorf("%q: expected an error", text)
+		}
+	}
+}
+
+func TestWriteListing_UsesSymbols(t *testing.T) {
+	rom := make([]byte, 2*ROM_BANK_SIZE)
+	// 0x0150: CALL 0x0158; JP 0xFF80; ...; 0x0158: RET
+	copy(rom[0x0150:], []byte{0xCD, 0x58, 0x01, 0xC3, 0x80, 0xFF})
+	rom[0x0158] = 0xC9
+
+	symbols, err := ParseSymbols(strings.NewReader(TEST_SYMBOLS))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out := &strings.Builder{}
+	if err := WriteListing(out, rom, 0, 0, symbols); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, expected := range []string{
+		"Main:\n    00:0150  CD 58 01  CALL Main.loop ",
+		"    00:0153  C3 80 FF  JP hDMARoutine ",
+		"Main.loop:\n    00:0158  C9        RET ",
+	} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("expected listing to contain %q", expected)
+		}
+	}
+}
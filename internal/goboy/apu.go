@@ -40,9 +40,9 @@ const (
 )
 
 const (
-	sampleRate      = 44100
+	SAMPLE_RATE     = 44100
 	clocksPerSecond = 4194304
-	clocksPerSample = clocksPerSecond / sampleRate / 2
+	clocksPerSample = clocksPerSecond / SAMPLE_RATE / 2
 	clocksPerFrame  = 8192
 )
 
@@ -365,3 +365,25 @@ func (apu *APU) readSoundOnOffReg() byte {
 
 	return out
 }
+
+func (apu *APU) saveState(s *stateWriter) {
+	s.write(apu.LeftSample, apu.RightSample, apu.NumSamples)
+	s.write(apu.LastLeft, apu.LastRight, apu.LastCorrectedLeft, apu.LastCorrectedRight)
+	s.write(apu.masterEnable, apu.frameSequencerCounter, apu.frameSequencer)
+	s.write(apu.VInToLeftSpeaker, apu.VInToRightSpeaker, apu.RightSpeakerVolume, apu.LeftSpeakerVolume)
+
+	for i := range apu.soundChannels {
+		apu.soundChannels[i].saveState(s)
+	}
+}
+
+func (apu *APU) loadState(s *stateReader) {
+	s.read(&apu.LeftSample, &apu.RightSample, &apu.NumSamples)
+	s.read(&apu.LastLeft, &apu.LastRight, &apu.LastCorrectedLeft, &apu.LastCorrectedRight)
+	s.read(&apu.masterEnable, &apu.frameSequencerCounter, &apu.frameSequencer)
+	s.read(&apu.VInToLeftSpeaker, &apu.VInToRightSpeaker, &apu.RightSpeakerVolume, &apu.LeftSpeakerVolume)
+
+	for i := range apu.soundChannels {
+		apu.soundChannels[i].loadState(s)
+	}
+}
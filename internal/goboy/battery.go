@@ -0,0 +1,135 @@
+package goboy
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Cartridges with a battery keep their RAM powered while the Game Boy is
+// switched off. This is emulated by writing the RAM out to a .sav file using
+// the same raw layout as most other emulators - every RAM bank one after the
+// other - so saves can be moved between them.
+
+// Games tend to write saves in bursts spread over several frames, so we wait
+// for RAM to settle for this many frames before flushing it to disk
+const BATTERY_FLUSH_FRAMES = 60
+
+var BATTERY_CARTRIDGE_TYPES = map[byte]bool{
+	0x03: true, // MBC1+RAM+BATTERY
+	0x06: true, // MBC2+BATTERY
+	0x09: true, // ROM+RAM+BATTERY
+	0x0D: true, // MMM01+RAM+BATTERY
+	0x0F: true, // MBC3+TIMER+BATTERY
+	0x10: true, // MBC3+TIMER+RAM+BATTERY
+	0x13: true, // MBC3+RAM+BATTERY
+	0x1B: true, // MBC5+RAM+BATTERY
+	0x1E: true, // MBC5+RUMBLE+RAM+BATTERY
+	0x22: true, // MBC7+SENSOR+RUMBLE+RAM+BATTERY
+	0xFC: true, // POCKET CAMERA
+	0xFF: true, // HuC1+RAM+BATTERY
+}
+
+func (c *Cartridge) HasBattery() bool {
+	return BATTERY_CARTRIDGE_TYPES[c.header.cartridgeType]
+}
+
+// Cartridges loaded from memory rather than a file have nowhere to save to
+func (c *Cartridge) persistsBattery() bool {
+	return c.HasBattery() && c.savePath != ""
+}
+
+// Saves are named after the ROM, and live next to it unless a save directory
+// has been given
+func savePathFor(romPath string, saveDir string, extension string) string {
+	name := strings.TrimSuffix(filepath.Base(romPath), filepath.Ext(romPath)) + extension
+
+	if saveDir == "" {
+		return filepath.Join(filepath.Dir(romPath), name)
+	}
+
+	return filepath.Join(saveDir, name)
+}
+
+func (c *Cartridge) loadBattery() error {
+	data, err := os.ReadFile(c.savePath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to load save: %w", err)
+	}
+
+	for _, bank := range c.ramBanks {
+		data = data[copy(bank.data, data):]
+	}
+
+	// Whatever is left after RAM is the clock, if the save has one
+	if c.rtc != nil && len(data) >= RTC_SAVE_FOOTER_SIZE_OLD {
+		c.rtc.loadFooter(data)
+	}
+
+	return nil
+}
+
+func (c *Cartridge) SaveBattery() error {
+	if !c.persistsBattery() {
+		return nil
+	}
+
+	data := make([]byte, 0)
+	for _, bank := range c.ramBanks {
+		data = append(data, bank.data...)
+	}
+
+	if c.rtc != nil {
+		data = append(data, c.rtc.saveFooter()...)
+	}
+
+	// The save directory might not have been used before
+	if err := os.MkdirAll(filepath.Dir(c.savePath), 0700); err != nil {
+		return fmt.Errorf("failed to write save: %w", err)
+	}
+
+	// Write to a temporary file first so that a crash half way through writing
+	// doesn't destroy the previous save
+	tmpPath := c.savePath + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0600); err != nil {
+		return fmt.Errorf("failed to write save: %w", err)
+	}
+
+	if err := os.Rename(tmpPath, c.savePath); err != nil {
+		return fmt.Errorf("failed to write save: %w", err)
+	}
+
+	c.ramDirty = false
+
+	return nil
+}
+
+// Called once per frame to flush RAM to disk once it has stopped changing
+func (c *Cartridge) tickBattery() {
+	if !c.ramDirty {
+		return
+	}
+
+	c.framesSinceRamWrite++
+	if c.framesSinceRamWrite < BATTERY_FLUSH_FRAMES {
+		return
+	}
+
+	if err := c.SaveBattery(); err != nil {
+		fmt.Println(err)
+	}
+}
+
+func (c *Cartridge) markRamDirty() {
+	if !c.persistsBattery() {
+		return
+	}
+
+	c.ramDirty = true
+	c.framesSinceRamWrite = 0
+}
@@ -0,0 +1,53 @@
+package goboy
+
+// @see https://gbdev.io/pandocs/Power_Up_Sequence.html
+const (
+	BOOT_ROM_SIZE = 0x100
+	// Writing a non-zero value here unmaps the boot ROM until the next reset
+	IO_BOOT = 0xFF50
+)
+
+// Without a boot ROM the hardware is created in the state the boot ROM would
+// have left it in, so it can jump straight to the cartridge. With one, the
+// hardware starts from scratch and the boot ROM sets it up itself.
+//
+// This puts the CPU registers, the timer (DIV, TIMA, TMA and TAC), the LCD
+// registers and palettes, and the APU back in their power-on state. IF is
+// already created cleared, and memory is left zeroed rather than random.
+func (gameboy *GameBoy) mapBootRom() {
+	gameboy.bus.(*Bus).bootRom = gameboy.bootRom
+
+	*gameboy.cpu.registers = CpuRegisters{}
+
+	timer := gameboy.timer
+	timer.sysclk = 0
+	timer.div = 0
+	timer.tima = 0
+	timer.tma = 0
+	// TAC's unused bits read as 1, so it's 0xF8 whether or not the boot ROM ran
+	timer.tac = 0xF8
+	timer.timerBit = timerBitMap[0]
+
+	lcd := gameboy.ppu.lcd
+	lcd.lcdc = 0
+	lcd.stat = 0
+	lcd.ly = 0
+	lcd.lyc = 0
+	lcd.scy = 0
+	lcd.scx = 0
+	lcd.wx = 0
+	lcd.wy = 0
+	lcd.bgp = 0
+	lcd.obj0 = 0
+	lcd.obj1 = 0
+	lcd.updatePalette(&lcd.bgColors, lcd.bgp)
+	lcd.updatePalette(&lcd.sp1Colors, lcd.obj0)
+	lcd.updatePalette(&lcd.sp2Colors, lcd.obj1)
+
+	// The boot ROM switches the APU on itself before playing the chime
+	gameboy.apu.writeSoundOnOffReg(0)
+}
+
+func (bus *Bus) unmapBootRom() {
+	bus.bootRom = nil
+}
@@ -0,0 +1,98 @@
+package goboy
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func newBootRomTestGameBoy(t *testing.T) *GameBoy {
+	t.Helper()
+
+	// LD A, 0x01; LDH (0x50), A; and then NOPs
+	bootRom := make([]byte, BOOT_ROM_SIZE)
+	copy(bootRom, []byte{0x3E, 0x01, 0xE0, 0x50})
+
+	options := DefaultOptions()
+	options.RomPath = TEST_ROM_PATH
+	options.BootRomPath = filepath.Join(t.TempDir(), "dmg_boot.bin")
+	if err := os.WriteFile(options.BootRomPath, bootRom, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	gameboy, err := NewGameBoy(options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return gameboy
+}
+
+func TestBootRom_StartsFromPowerOnState(t *testing.T) {
+	gameboy := newBootRomTestGameBoy(t)
+
+	if pc := gameboy.ReadRegister(R_PC); pc != 0x0000 {
+		t.Errorf("expected to start at 0x0000, got 0x%4.4X", pc)
+	}
+
+	if a := gameboy.ReadRegister(R_A); a != 0x00 {
+		t.Errorf("expected A to start cleared, got 0x%2.2X", a)
+	}
+
+	if lcdc := gameboy.ReadMemory(LCD_LCDC); lcdc != 0x00 {
+		t.Errorf("expected the LCD to start switched off, got LCDC 0x%2.2X", lcdc)
+	}
+
+	if div := gameboy.ReadMemory(TIMER_DIV); div != 0x00 {
+		t.Errorf("expected DIV to start at 0, got 0x%2.2X", div)
+	}
+
+	if tac := gameboy.ReadMemory(TIMER_TAC); tac != 0xF8 {
+		t.Errorf("expected the timer to start stopped, got TAC 0x%2.2X", tac)
+	}
+
+	if stat := gameboy.ReadMemory(LCD_STAT); stat != 0x00 {
+		t.Errorf("expected STAT to start cleared, got 0x%2.2X", stat)
+	}
+
+	if nr52 := gameboy.ReadMemory(APU_NR52); nr52&0x80 != 0 {
+		t.Errorf("expected the APU to start switched off, got NR52 0x%2.2X", nr52)
+	}
+}
+
+func TestBootRom_UnmapsOnWrite(t *testing.T) {
+	gameboy := newBootRomTestGameBoy(t)
+
+	if value := gameboy.ReadMemory(0x0000); value != 0x3E {
+		t.Fatalf("expected the boot ROM to be mapped at 0x0000, got 0x%2.2X", value)
+	}
+
+	if value := gameboy.ReadMemory(0x0100); value != gameboy.cartridge.romData[0x0100] {
+		t.Errorf("expected the cartridge to be visible past the boot ROM, got 0x%2.2X", value)
+	}
+
+	gameboy.Step()
+	gameboy.Step()
+
+	if value := gameboy.ReadMemory(0x0000); value != gameboy.cartridge.romData[0x0000] {
+		t.Errorf("expected the cartridge to be mapped after writing to 0xFF50, got 0x%2.2X", value)
+	}
+
+	gameboy.Reset()
+
+	if value := gameboy.ReadMemory(0x0000); value != 0x3E {
+		t.Errorf("expected resetting to map the boot ROM again, got 0x%2.2X", value)
+	}
+}
+
+func TestBootRom_SkippedByDefault(t *testing.T) {
+	gameboy := newTestGameBoy(t)
+
+	if pc := gameboy.ReadRegister(R_PC); pc != 0x0100 {
+		t.Errorf("expected to start at the cartridge entry point, got 0x%4.4X", pc)
+	}
+
+	if lcdc := gameboy.ReadMemory(LCD_LCDC); lcdc != 0x91 {
+		t.Errorf("expected the post boot LCDC value, got 0x%2.2X", lcdc)
+	}
+}
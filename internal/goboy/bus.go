@@ -61,6 +61,12 @@ type Bus struct {
 	hram                    *RAM
 	io                      *IO
 	interruptEnableRegister *InterruptRegister
+
+	// Overlays the start of the cartridge until it's unmapped through IO_BOOT
+	bootRom []byte
+
+	// Called on reads and writes that go through readByte and writeByte
+	hooks *MemoryHooks
 }
 
 func (bus *Bus) Init(
@@ -70,6 +76,7 @@ func (bus *Bus) Init(
 	hram *RAM,
 	io *IO,
 	interruptEnableRegister *InterruptRegister,
+	hooks *MemoryHooks,
 ) {
 	bus.cartridge = cartridge
 	bus.ppu = ppu
@@ -77,10 +84,36 @@ func (bus *Bus) Init(
 	bus.hram = hram
 	bus.io = io
 	bus.interruptEnableRegister = interruptEnableRegister
+	bus.hooks = hooks
 }
 
+// Reads a byte on behalf of the CPU or DMA, calling any hooks watching it
 func (bus *Bus) readByte(address uint16) byte {
+	value := bus.peekByte(address)
+
+	if bus.hooks.watching(HOOK_READ, address) {
+		bus.hooks.fire(HOOK_READ, address, value, bus.cartridge)
+	}
+
+	return value
+}
+
+// Writes a byte on behalf of the CPU or DMA, calling any hooks watching it
+func (bus *Bus) writeByte(address uint16, value byte) {
+	bus.pokeByte(address, value)
+
+	if bus.hooks.watching(HOOK_WRITE, address) {
+		bus.hooks.fire(HOOK_WRITE, address, value, bus.cartridge)
+	}
+}
+
+// Reads a byte without calling any hooks, for the rest of the hardware looking
+// at its own registers, and tools looking at memory
+func (bus *Bus) peekByte(address uint16) byte {
 	if address <= SWITCHABLE_ROM_BANK_END {
+		if bus.bootRom != nil && address < BOOT_ROM_SIZE {
+			return bus.bootRom[address]
+		}
 		return bus.cartridge.readByte(address)
 	} else if address <= VIDEO_RAM_END {
 		return bus.ppu.readByte(address)
@@ -89,7 +122,7 @@ func (bus *Bus) readByte(address uint16) byte {
 	} else if address <= SWITCHABLE_WORK_RAM_END {
 		return bus.wram.readByte(address)
 	} else if address <= ECHO_RAM_END {
-		return bus.readByte(address - 0x2000)
+		return bus.peekByte(address - 0x2000)
 	} else if address <= OAM_END {
 		return bus.ppu.readByte(address)
 	} else if address <= NOT_USABLE_END {
@@ -105,7 +138,8 @@ func (bus *Bus) readByte(address uint16) byte {
 	panic("Somehow didn't manage to read a byte")
 }
 
-func (bus *Bus) writeByte(address uint16, value byte) {
+// Writes a byte without calling any hooks
+func (bus *Bus) pokeByte(address uint16, value byte) {
 	if address <= SWITCHABLE_ROM_BANK_END {
 		bus.cartridge.writeByte(address, value)
 		return
@@ -119,7 +153,7 @@ func (bus *Bus) writeByte(address uint16, value byte) {
 		bus.wram.writeByte(address, value)
 		return
 	} else if address <= ECHO_RAM_END {
-		bus.writeByte(address-0x2000, value)
+		bus.pokeByte(address-0x2000, value)
 		return
 	} else if address <= OAM_END {
 		bus.ppu.writeByte(address, value)
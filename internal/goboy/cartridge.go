@@ -2,6 +2,7 @@ package goboy
 
 import (
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"os"
 )
@@ -337,24 +338,50 @@ func mapToFriendlyName[K comparable](m map[K]string, k K) string {
 }
 
 type Cartridge struct {
-	filename     string
-	romData      []byte
-	header       RomHeader
-	ramBanks     []*RAM
-	ramBankIndex byte
-	romBankIndex byte
-	ramEnabled   bool
+	filename string
+	romData  []byte
+	header   RomHeader
+	ramBanks []*RAM
+	rtc      *RTC
+	mbc      MBC
+
+	rumbleCallbacks []RumbleCallback
+
+	// The Game Genie codes which are turned on
+	gameGenieCodes []Cheat
+
+	// Where battery backed RAM is persisted
+	savePath            string
+	ramDirty            bool
+	framesSinceRamWrite int
 }
 
-func LoadCartridge(path string) *Cartridge {
-	if DEBUG {
-		fmt.Printf("Loading cartridge from %s\n", path)
+func LoadCartridge(path string, saveDir string) (*Cartridge, error) {
+	if path == "" {
+		return nil, errors.New("failed to load cartridge: no ROM path given")
 	}
 
 	romData, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load cartridge: %w", err)
+	}
 
+	cartridge, err := NewCartridge(romData, savePathFor(path, saveDir, ".sav"))
 	if err != nil {
-		panic(err)
+		return nil, err
+	}
+	cartridge.filename = path
+
+	return cartridge, nil
+}
+
+// Builds a cartridge from a ROM image that's already in memory. Battery backed
+// RAM is loaded from and saved to savePath, or not persisted at all if it's
+// empty.
+func NewCartridge(romData []byte, savePath string) (*Cartridge, error) {
+	// Anything smaller than this can't even hold a complete header
+	if len(romData) < 0x150 {
+		return nil, fmt.Errorf("failed to load cartridge: too small to be a ROM (%d bytes)", len(romData))
 	}
 
 	header := RomHeader{}
@@ -374,25 +401,64 @@ func LoadCartridge(path string) *Cartridge {
 		[]byte{romData[0x14E], romData[0x14F]},
 	)
 
+	if _, ok := ROM_SIZE_MAP[header.romSize]; !ok {
+		return nil, fmt.Errorf("failed to load cartridge: unknown ROM size 0x%2.2X", header.romSize)
+	}
+
+	if _, ok := RAM_SIZE_MAP[header.ramSize]; !ok {
+		return nil, fmt.Errorf("failed to load cartridge: unknown RAM size 0x%2.2X", header.ramSize)
+	}
+
 	// @see https://gbdev.io/pandocs/The_Cartridge_Header.html#0148--rom-size
 	romSize := (32 * 1024) * (1 << romData[0x0148])
 	buffer := make([]byte, romSize)
 	copy(buffer, romData)
 
 	cartridge := Cartridge{
-		filename:     path,
-		romData:      buffer,
-		header:       header,
-		romBankIndex: 1,
+		romData:  buffer,
+		header:   header,
+		savePath: savePath,
 	}
 
+	mbc, err := NewMBC(&cartridge)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load cartridge: %w", err)
+	}
+	cartridge.mbc = mbc
+
 	cartridge.initRamBanks()
-	cartridge.debugPrint()
 
-	return &cartridge
+	if RTC_CARTRIDGE_TYPES[header.cartridgeType] {
+		cartridge.rtc = NewRTC()
+	}
+
+	if cartridge.persistsBattery() {
+		if err := cartridge.loadBattery(); err != nil {
+			return nil, err
+		}
+	}
+
+	return &cartridge, nil
+}
+
+// Puts the controller back in its power on state. RAM and the clock are kept,
+// as they would be on a real cartridge.
+func (c *Cartridge) reset() {
+	if mbc, ok := c.mbc.(*MBC5); ok {
+		mbc.setRumbling(false)
+	}
+
+	// This can't fail, as the same cartridge type has already been loaded once
+	c.mbc, _ = NewMBC(c)
 }
 
 func (c *Cartridge) initRamBanks() {
+	// MBC2 carts report no RAM in their header, as it's part of the controller
+	if MBC2_CARTRIDGE_TYPES[c.header.cartridgeType] {
+		c.ramBanks = []*RAM{NewRAM(MBC2_RAM_SIZE, EXTERNAL_RAM_START)}
+		return
+	}
+
 	bankMap := []byte{0, 0, 1, 4, 16, 8}
 	bankCount := bankMap[c.header.ramSize]
 
@@ -406,10 +472,6 @@ func (c *Cartridge) initRamBanks() {
 }
 
 func (cartridge *Cartridge) debugPrint() {
-	// if !DEBUG {
-	// 	return
-	// }
-
 	h := cartridge.header
 
 	fmt.Println("Loaded cartridge:")
@@ -461,70 +523,140 @@ func (cartridge *Cartridge) calculateGlobalChecksum() uint16 {
 }
 
 func (c *Cartridge) readByte(address uint16) byte {
-	switch {
-	case address <= ROM_BANK_0_END:
-		return c.romData[address]
-
-	case address <= SWITCHABLE_ROM_BANK_END:
-		bankOffset := uint32(address-0x4000) + uint32(c.romBankIndex)*0x4000
-		return c.romData[bankOffset]
-
-	case address <= SWITCHABLE_WORK_RAM_END:
-		if c.ramBankIndex <= 0x03 {
-			return c.ramBanks[c.ramBankIndex].readByte(address)
-		} else {
-			return c.readRTCRegister(c.ramBankIndex)
-		}
+	value := c.mbc.readByte(address)
 
-	default:
-		panic(fmt.Sprintf("Failed to read from cartridge at 0x%4.4X", address))
+	if len(c.gameGenieCodes) != 0 && address <= SWITCHABLE_ROM_BANK_END {
+		return c.applyGameGenie(address, value)
 	}
+
+	return value
 }
 
 func (c *Cartridge) writeByte(address uint16, value byte) {
-	switch {
-	case Between(address, 0x0000, 0x1FFF):
-		c.ramEnabled = (value & 0x0F) == 0x0A
-
-	case Between(address, 0x2000, 0x3FFF):
-		bank := value & 0x7F
-		if bank == 0 {
-			bank = 1
-		}
-		c.romBankIndex = bank
+	c.mbc.writeByte(address, value)
+}
 
-	case Between(address, 0x4000, 0x5FFF):
-		if value <= 0x03 {
-			c.ramBankIndex = value
-		} else if value >= 0x08 && value <= 0x0C {
-			c.ramBankIndex = value
-		}
+// Reads from a 16 KiB ROM bank. Bank numbers wrap around to the size of the
+// ROM, in the same way the unconnected upper address lines would
+func (c *Cartridge) readRom(bank int, address uint16) byte {
+	offset := (bank%c.romBankCount())*0x4000 + int(address&0x3FFF)
 
-	case Between(address, 0x6000, 0x7FFF):
-		if value == 1 {
-			c.latchClockData()
-		}
+	// Only ROMs smaller than a single bank can be read past the end
+	if offset >= len(c.romData) {
+		return 0xFF
+	}
 
-	case Between(address, 0xA000, 0xBFFF):
-		if c.ramEnabled {
-			if c.ramBankIndex <= 0x03 {
-				c.ramBanks[c.ramBankIndex].writeByte(address, value)
-			} else {
-				c.writeRTCRegister(c.ramBankIndex, value)
-			}
-		}
+	return c.romData[offset]
+}
+
+func (c *Cartridge) romBankCount() int {
+	return max(len(c.romData)/0x4000, 1)
+}
+
+// The ROM bank visible at an address, or -1 if the address isn't in ROM
+func (c *Cartridge) romBankAt(address uint16) int {
+	if address > SWITCHABLE_ROM_BANK_END {
+		return -1
+	}
+
+	return c.mbc.mappedRomBank(address) % c.romBankCount()
+}
+
+// The cartridge RAM bank visible at an address, or -1 if the address isn't in
+// cartridge RAM or there's something other than RAM mapped there
+func (c *Cartridge) ramBankAt(address uint16) int {
+	if !Between(address, EXTERNAL_RAM_START, EXTERNAL_RAM_END) {
+		return -1
+	}
+
+	bank := c.mbc.mappedRamBank()
+	if bank < 0 || len(c.ramBanks) == 0 {
+		return bank
+	}
 
-	default:
-		panic(fmt.Sprintf("Can't write to cartridge at 0x%4.4X=0x%2.2X", address, value))
+	return bank % len(c.ramBanks)
+}
+
+func (c *Cartridge) readRam(bank int, address uint16) byte {
+	if len(c.ramBanks) == 0 {
+		return 0xFF
 	}
+
+	return c.ramBanks[bank%len(c.ramBanks)].readByte(address)
 }
 
-func (c *Cartridge) readRTCRegister(index byte) byte {
-	return 0
+func (c *Cartridge) writeRam(bank int, address uint16, value byte) {
+	if len(c.ramBanks) == 0 {
+		return
+	}
+
+	c.ramBanks[bank%len(c.ramBanks)].writeByte(address, value)
+	c.markRamDirty()
 }
 
-func (c *Cartridge) writeRTCRegister(index byte, value byte) {
+func (c *Cartridge) saveState(s *stateWriter) {
+	// States only make sense for the ROM they were made with, so keep enough of
+	// the header around to check that
+	s.write(c.header.title, c.header.globalChecksum)
+	c.mbc.saveState(s)
+
+	s.write(uint16(len(c.ramBanks)))
+	for _, bank := range c.ramBanks {
+		s.write(bank.data)
+	}
+
+	s.write(c.rtc != nil)
+	if c.rtc != nil {
+		c.rtc.saveState(s)
+	}
 }
 
-func (c *Cartridge) latchClockData() {
+func (c *Cartridge) loadState(s *stateReader) {
+	var title [16]byte
+	var globalChecksum uint16
+	s.read(&title, &globalChecksum)
+
+	if s.err == nil && (title != c.header.title || globalChecksum != c.header.globalChecksum) {
+		s.fail(ErrSaveStateRomMismatch)
+		return
+	}
+
+	// Before version 3 every cartridge was banked like an MBC3, so those states
+	// can't be mapped on to any other controller
+	if _, ok := c.mbc.(*MBC3); s.version < 3 && !ok {
+		s.fail(UnsupportedSaveStateVersionError{Version: s.version})
+		return
+	}
+
+	c.mbc.loadState(s)
+
+	var bankCount uint16
+	s.read(&bankCount)
+
+	if s.err == nil && int(bankCount) != len(c.ramBanks) {
+		s.fail(ErrSaveStateRomMismatch)
+		return
+	}
+
+	for _, bank := range c.ramBanks {
+		s.read(bank.data)
+	}
+
+	// The clock was added in version 2, older states just keep the clock running
+	// from wherever it currently is
+	if s.version < 2 {
+		return
+	}
+
+	var hasRtc bool
+	s.read(&hasRtc)
+
+	if s.err == nil && hasRtc != (c.rtc != nil) {
+		s.fail(ErrSaveStateRomMismatch)
+		return
+	}
+
+	if hasRtc {
+		c.rtc.loadState(s)
+	}
 }
@@ -0,0 +1,397 @@
+package goboy
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Builds a ROM with a valid enough header to be loaded, with every ROM bank
+// filled with its own bank number so bank switching can be checked. Banks past
+// 0xFF also have their upper bits in the second byte of the bank.
+func writeTestRom(t *testing.T, cartridgeType byte, romSize byte, ramSize byte) string {
+	t.Helper()
+
+	data := make([]byte, (32*1024)*(1<<romSize))
+	for i := range data {
+		data[i] = byte(i / 0x4000)
+	}
+	for bank := 0x100; bank < len(data)/0x4000; bank++ {
+		data[bank*0x4000+1] = byte(bank >> 8)
+	}
+
+	copy(data[0x0134:], "TEST ROM")
+	data[0x0147] = cartridgeType
+	data[0x0148] = romSize
+	data[0x0149] = ramSize
+
+	path := filepath.Join(t.TempDir(), "test.gb")
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestLoadCartridge_MissingFile(t *testing.T) {
+	_, err := LoadCartridge(filepath.Join(t.TempDir(), "missing.gb"), "")
+	if err == nil {
+		t.Fatal("expected an error loading a missing ROM")
+	}
+}
+
+func TestLoadCartridge_TooSmall(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "small.gb")
+	if err := os.WriteFile(path, make([]byte, 0x100), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := LoadCartridge(path, "")
+	if err == nil {
+		t.Fatal("expected an error loading a truncated ROM")
+	}
+}
+
+func TestLoadCartridge_UnsupportedType(t *testing.T) {
+	// MBC6
+	_, err := LoadCartridge(writeTestRom(t, 0x20, 0x02, 0x00), "")
+
+	var unsupported UnsupportedCartridgeError
+	if !errors.As(err, &unsupported) || unsupported.CartridgeType != 0x20 {
+		t.Fatalf("expected an unsupported cartridge error, got %v", err)
+	}
+}
+
+func TestRomOnly_IgnoresBankSwitching(t *testing.T) {
+	cartridge, err := LoadCartridge(writeTestRom(t, 0x00, 0x00, 0x00), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cartridge.writeByte(0x2000, 0x05)
+
+	if bank := cartridge.readByte(0x4000); bank != 1 {
+		t.Errorf("expected ROM bank 1 to stay mapped, got bank %d", bank)
+	}
+}
+
+func TestBattery_SaveAndLoad(t *testing.T) {
+	// MBC3+RAM+BATTERY with 32 KiB of RAM
+	romPath := writeTestRom(t, 0x13, 0x02, 0x03)
+	saveDir := t.TempDir()
+
+	cartridge, err := LoadCartridge(romPath, saveDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cartridge.writeByte(0x0000, 0x0A)
+	cartridge.writeByte(0x4000, 0x02)
+	cartridge.writeByte(0xA123, 0x42)
+
+	if !cartridge.ramDirty {
+		t.Fatal("expected RAM to be dirty after a write")
+	}
+
+	if err := cartridge.SaveBattery(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(saveDir, "test.sav"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(data) != 4*0x2000 {
+		t.Fatalf("expected save to be 32 KiB, got %d bytes", len(data))
+	}
+
+	if data[2*0x2000+0x123] != 0x42 {
+		t.Errorf("expected bank 2 to be saved in the raw layout")
+	}
+
+	reloaded, err := LoadCartridge(romPath, saveDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(reloaded.ramBanks[2].data, cartridge.ramBanks[2].data) {
+		t.Errorf("expected RAM to be restored from the save")
+	}
+}
+
+func TestBattery_CreatesSaveDir(t *testing.T) {
+	// MBC1+RAM+BATTERY
+	romPath := writeTestRom(t, 0x03, 0x02, 0x03)
+	saveDir := filepath.Join(t.TempDir(), "saves", "gb")
+
+	cartridge, err := LoadCartridge(romPath, saveDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := cartridge.SaveBattery(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(filepath.Join(saveDir, "test.sav")); err != nil {
+		t.Errorf("expected the save directory to be created, got %v", err)
+	}
+}
+
+func TestBattery_FlushesAfterWritesSettle(t *testing.T) {
+	romPath := writeTestRom(t, 0x13, 0x02, 0x02)
+	cartridge, err := LoadCartridge(romPath, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cartridge.writeByte(0x0000, 0x0A)
+	cartridge.writeByte(0xA000, 0x01)
+
+	for i := 0; i < BATTERY_FLUSH_FRAMES-1; i++ {
+		cartridge.tickBattery()
+	}
+
+	if _, err := os.Stat(cartridge.savePath); !os.IsNotExist(err) {
+		t.Fatalf("expected no save before RAM settles, got %v", err)
+	}
+
+	cartridge.tickBattery()
+
+	if _, err := os.Stat(cartridge.savePath); err != nil {
+		t.Fatalf("expected a save once RAM settles, got %v", err)
+	}
+}
+
+func TestMBC1_RomBanking(t *testing.T) {
+	// MBC1 with 2 MiB of ROM
+	cartridge, err := LoadCartridge(writeTestRom(t, 0x01, 0x06, 0x00), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		romBank   byte
+		upperBank byte
+		expected  byte
+	}{
+		{0x00, 0x00, 0x01},
+		{0x05, 0x00, 0x05},
+		{0x1F, 0x03, 0x7F},
+		// Bank 0x20 can't be selected, the zero check only sees the lower 5 bits
+		{0x00, 0x01, 0x21},
+		// Only 5 bits of the ROM bank register are wired up
+		{0x25, 0x00, 0x05},
+	}
+
+	for _, test := range tests {
+		cartridge.writeByte(0x2000, test.romBank)
+		cartridge.writeByte(0x4000, test.upperBank)
+
+		if bank := cartridge.readByte(0x4000); bank != test.expected {
+			t.Errorf("ROM bank 0x%2.2X, upper bank %d: expected bank 0x%2.2X, got 0x%2.2X", test.romBank, test.upperBank, test.expected, bank)
+		}
+	}
+}
+
+func TestMBC1_BankingMode(t *testing.T) {
+	// MBC1+RAM with 2 MiB of ROM and 32 KiB of RAM
+	cartridge, err := LoadCartridge(writeTestRom(t, 0x02, 0x06, 0x03), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if value := cartridge.readByte(0xA000); value != 0xFF {
+		t.Errorf("expected disabled RAM to read 0xFF, got 0x%2.2X", value)
+	}
+
+	cartridge.writeByte(0x0000, 0x0A)
+	cartridge.writeByte(0x4000, 0x02)
+
+	// In mode 0 the upper bank register only applies to the switchable ROM area
+	cartridge.writeByte(0xA000, 0x11)
+	if bank := cartridge.readByte(0x0000); bank != 0x00 {
+		t.Errorf("expected bank 0 to be mapped in mode 0, got bank 0x%2.2X", bank)
+	}
+	if cartridge.ramBanks[0].data[0] != 0x11 {
+		t.Errorf("expected RAM bank 0 to be mapped in mode 0")
+	}
+
+	cartridge.writeByte(0x6000, 0x01)
+
+	cartridge.writeByte(0xA000, 0x22)
+	if bank := cartridge.readByte(0x0000); bank != 0x40 {
+		t.Errorf("expected bank 0x40 to be mapped in mode 1, got bank 0x%2.2X", bank)
+	}
+	if cartridge.ramBanks[2].data[0] != 0x22 {
+		t.Errorf("expected RAM bank 2 to be mapped in mode 1")
+	}
+}
+
+func TestMBC1_Multicart(t *testing.T) {
+	// MBC1 with 1 MiB of ROM
+	path := writeTestRom(t, 0x01, 0x05, 0x00)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Every game on a multicart has its own header, and so its own logo
+	logo := bytes.Repeat([]byte{0xCE, 0xED, 0x66, 0x66}, 12)
+	for _, game := range []int{0x00, 0x10, 0x20, 0x30} {
+		copy(data[game*0x4000+0x0104:], logo)
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cartridge, err := LoadCartridge(path, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if mbc := cartridge.mbc.(*MBC1); !mbc.multicart {
+		t.Fatal("expected the ROM to be detected as an MBC1M multicart")
+	}
+
+	cartridge.writeByte(0x4000, 0x01)
+	cartridge.writeByte(0x2000, 0x12)
+	cartridge.writeByte(0x6000, 0x01)
+
+	if bank := cartridge.readByte(0x4000); bank != 0x12 {
+		t.Errorf("expected bank 0x12, got 0x%2.2X", bank)
+	}
+
+	if bank := cartridge.readByte(0x0000); bank != 0x10 {
+		t.Errorf("expected bank 0x10 to be mapped in mode 1, got 0x%2.2X", bank)
+	}
+}
+
+func TestMBC5_RomBanking(t *testing.T) {
+	// MBC5 with 8 MiB of ROM
+	cartridge, err := LoadCartridge(writeTestRom(t, 0x19, 0x08, 0x00), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	readBank := func() uint16 {
+		return uint16(cartridge.readByte(0x4001))<<8 | uint16(cartridge.readByte(0x4000))
+	}
+
+	cartridge.writeByte(0x2000, 0x00)
+	if bank := readBank(); bank != 0x000 {
+		t.Errorf("expected bank 0 to be selectable, got bank 0x%3.3X", bank)
+	}
+
+	cartridge.writeByte(0x2000, 0x45)
+	cartridge.writeByte(0x3000, 0x01)
+	if bank := readBank(); bank != 0x145 {
+		t.Errorf("expected bank 0x145, got bank 0x%3.3X", bank)
+	}
+
+	cartridge.writeByte(0x2000, 0xFF)
+	if bank := readBank(); bank != 0x1FF {
+		t.Errorf("expected writing the low bits to keep the high bit, got bank 0x%3.3X", bank)
+	}
+}
+
+func TestMBC5_Rumble(t *testing.T) {
+	// MBC5+RUMBLE+RAM+BATTERY with 128 KiB of RAM
+	cartridge, err := LoadCartridge(writeTestRom(t, 0x1E, 0x02, 0x04), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var events []bool
+	cartridge.rumbleCallbacks = append(cartridge.rumbleCallbacks, func(on bool) {
+		events = append(events, on)
+	})
+
+	cartridge.writeByte(0x0000, 0x0A)
+	cartridge.writeByte(0x4000, 0x08)
+	cartridge.writeByte(0x4000, 0x0B)
+	cartridge.writeByte(0xA000, 0x42)
+	cartridge.writeByte(0x4000, 0x03)
+
+	if len(events) != 2 || !events[0] || events[1] {
+		t.Errorf("expected the motor to turn on then off once each, got %v", events)
+	}
+
+	// The motor bit isn't part of the RAM bank number
+	if cartridge.ramBanks[3].data[0] != 0x42 {
+		t.Errorf("expected RAM bank 3 to be mapped with the motor running")
+	}
+}
+
+func TestMBC2_Registers(t *testing.T) {
+	// MBC2 with 256 KiB of ROM
+	cartridge, err := LoadCartridge(writeTestRom(t, 0x05, 0x03, 0x00), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Address bit 8 clear is the RAM enable register, set is the ROM bank
+	cartridge.writeByte(0x0000, 0x0A)
+	cartridge.writeByte(0x2100, 0x05)
+	if bank := cartridge.readByte(0x4000); bank != 5 {
+		t.Errorf("expected bank 5, got bank %d", bank)
+	}
+
+	cartridge.writeByte(0x3E00, 0x03)
+	if bank := cartridge.readByte(0x4000); bank != 5 {
+		t.Errorf("expected writes with address bit 8 clear to leave the ROM bank alone, got bank %d", bank)
+	}
+
+	cartridge.writeByte(0x0100, 0x00)
+	if bank := cartridge.readByte(0x4000); bank != 1 {
+		t.Errorf("expected bank 0 to map bank 1, got bank %d", bank)
+	}
+}
+
+func TestMBC2_Ram(t *testing.T) {
+	// MBC2+BATTERY with 256 KiB of ROM
+	romPath := writeTestRom(t, 0x06, 0x03, 0x00)
+	saveDir := t.TempDir()
+
+	cartridge, err := LoadCartridge(romPath, saveDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cartridge.writeByte(0x0000, 0x0A)
+	cartridge.writeByte(0xA010, 0xAB)
+
+	if value := cartridge.readByte(0xA010); value != 0xFB {
+		t.Errorf("expected only the lower nibble to be stored, got 0x%2.2X", value)
+	}
+
+	if value := cartridge.readByte(0xB810); value != 0xFB {
+		t.Errorf("expected RAM to be mirrored every 512 bytes, got 0x%2.2X", value)
+	}
+
+	if err := cartridge.SaveBattery(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(cartridge.savePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(data) != MBC2_RAM_SIZE || data[0x10] != 0x0B {
+		t.Fatalf("expected a 512 byte save holding the stored nibbles, got %d bytes", len(data))
+	}
+
+	reloaded, err := LoadCartridge(romPath, saveDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reloaded.writeByte(0x0000, 0x0A)
+	if value := reloaded.readByte(0xA010); value != 0xFB {
+		t.Errorf("expected RAM to be restored from the save, got 0x%2.2X", value)
+	}
+}
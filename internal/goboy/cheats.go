@@ -0,0 +1,335 @@
+package goboy
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+type CheatFormat byte
+
+const (
+	// Patches what the CPU reads from ROM
+	// @see https://gbdev.gg8.se/wiki/articles/Gameboy_Game_Genie_Codes
+	CHEAT_GAME_GENIE CheatFormat = iota
+	// Writes a value to RAM once per frame
+	CHEAT_GAMESHARK
+)
+
+func (format CheatFormat) String() string {
+	if format == CHEAT_GAMESHARK {
+		return "GameShark"
+	}
+
+	return "Game Genie"
+}
+
+type Cheat struct {
+	// The code as it was given, upper cased, e.g. 00A-17B-C49 or 01FF16D0
+	Code        string
+	Description string
+	Enabled     bool
+
+	Format  CheatFormat
+	Address uint16
+	Value   byte
+	// Game Genie codes with a compare value only patch the byte if it was going
+	// to read as Compare, so they leave other ROM banks alone
+	Compare    byte
+	HasCompare bool
+	// The cartridge RAM bank a GameShark code writes to, or -1 for whichever
+	// is mapped
+	Bank int
+}
+
+// Decodes a Game Genie (ABC-DEF or ABC-DEF-GHI) or GameShark (ABCDEFGH) code.
+// The cheat starts off enabled.
+func ParseCheat(code string) (Cheat, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	digits := strings.ReplaceAll(code, "-", "")
+
+	if _, err := strconv.ParseUint(digits, 16, 64); err != nil {
+		return Cheat{}, fmt.Errorf("invalid cheat %q: expected hex digits", code)
+	}
+
+	if !isCheatLayout(code, len(digits)) {
+		return Cheat{}, fmt.Errorf("invalid cheat %q: expected ABC-DEF, ABC-DEF-GHI or ABCDEFGH", code)
+	}
+
+	var cheat Cheat
+	var err error
+	if len(digits) == 8 {
+		cheat, err = parseGameShark(digits)
+	} else {
+		cheat, err = parseGameGenie(digits)
+	}
+
+	if err != nil {
+		return Cheat{}, fmt.Errorf("invalid cheat %q: %w", code, err)
+	}
+
+	cheat.Code = code
+	cheat.Enabled = true
+	return cheat, nil
+}
+
+// Game Genie codes can be written with or without their dashes, but if they're
+// there they have to be in the right place. GameShark codes don't have any.
+func isCheatLayout(code string, digits int) bool {
+	if !strings.Contains(code, "-") {
+		return digits == 6 || digits == 8 || digits == 9
+	}
+
+	for _, group := range strings.Split(code, "-") {
+		if len(group) != 3 {
+			return false
+		}
+	}
+
+	return digits == 6 || digits == 9
+}
+
+// ABC-DEF-GHI: AB is the new value, FCDE the address with F inverted, and GI
+// the compare value, rotated right by 2 and XORed with 0xBA to decode it. H
+// isn't used.
+func parseGameGenie(digits string) (Cheat, error) {
+	digit := func(i int) uint16 {
+		value, _ := strconv.ParseUint(digits[i:i+1], 16, 4)
+		return uint16(value)
+	}
+
+	cheat := Cheat{
+		Format:  CHEAT_GAME_GENIE,
+		Value:   byte(digit(0)<<4 | digit(1)),
+		Address: (digit(5)^0xF)<<12 | digit(2)<<8 | digit(3)<<4 | digit(4),
+		Bank:    -1,
+	}
+
+	if cheat.Address > SWITCHABLE_ROM_BANK_END {
+		return Cheat{}, fmt.Errorf("Game Genie codes patch ROM, but this one is for 0x%4.4X", cheat.Address)
+	}
+
+	if len(digits) == 9 {
+		compare := byte(digit(6)<<4 | digit(8))
+		cheat.Compare = (compare>>2 | compare<<6) ^ 0xBA
+		cheat.HasCompare = true
+	}
+
+	return cheat, nil
+}
+
+// ABCDEFGH: AB is the type, CD the value and GHEF the address. Types 00 and 01
+// write to whatever's mapped, 80 to 8F write to cartridge RAM bank 0 to F.
+func parseGameShark(digits string) (Cheat, error) {
+	value, _ := strconv.ParseUint(digits, 16, 32)
+
+	kind := byte(value >> 24)
+	cheat := Cheat{
+		Format:  CHEAT_GAMESHARK,
+		Value:   byte(value >> 16),
+		Address: uint16(value&0xFF)<<8 | uint16(value>>8&0xFF),
+		Bank:    -1,
+	}
+
+	switch {
+	case kind == 0x00 || kind == 0x01:
+	case kind&0xF0 == 0x80:
+		cheat.Bank = int(kind & 0x0F)
+	default:
+		return Cheat{}, fmt.Errorf("unsupported GameShark code type %2.2X", kind)
+	}
+
+	if cheat.Address < VIDEO_RAM_START {
+		return Cheat{}, fmt.Errorf("GameShark codes write to RAM, but this one is for 0x%4.4X", cheat.Address)
+	}
+
+	return cheat, nil
+}
+
+// Describes what the cheat does, e.g. "4A17 = 00 if C8" or "C0F1 = 99"
+func (cheat Cheat) Effect() string {
+	effect := fmt.Sprintf("%4.4X = %2.2X", cheat.Address, cheat.Value)
+
+	if cheat.HasCompare {
+		effect += fmt.Sprintf(" if %2.2X", cheat.Compare)
+	}
+	if cheat.Bank >= 0 {
+		effect += fmt.Sprintf(" in bank %d", cheat.Bank)
+	}
+
+	return effect
+}
+
+// Reads a cheat file, which has a "code on|off description" line for each
+// cheat. Blank lines and anything after a # are ignored.
+func ParseCheats(reader io.Reader) ([]Cheat, error) {
+	cheats := []Cheat{}
+
+	scanner := bufio.NewScanner(reader)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		if len(fields) < 2 || (fields[1] != "on" && fields[1] != "off") {
+			return nil, fmt.Errorf("failed to load cheats: line %d: expected \"code on|off description\"", lineNumber)
+		}
+
+		cheat, err := ParseCheat(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("failed to load cheats: line %d: %w", lineNumber, err)
+		}
+
+		cheat.Enabled = fields[1] == "on"
+		cheat.Description = strings.Join(fields[2:], " ")
+		cheats = append(cheats, cheat)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to load cheats: %w", err)
+	}
+
+	return cheats, nil
+}
+
+func WriteCheats(writer io.Writer, cheats []Cheat) error {
+	out := bufio.NewWriter(writer)
+
+	fmt.Fprintln(out, "# code on|off description")
+	for _, cheat := range cheats {
+		state := "off"
+		if cheat.Enabled {
+			state = "on"
+		}
+
+		fmt.Fprintln(out, strings.TrimSpace(fmt.Sprintf("%s %s %s", cheat.Code, state, cheat.Description)))
+	}
+
+	return out.Flush()
+}
+
+// The cheat file used for a ROM unless another is given, e.g. game.cht for
+// game.gb
+func CheatPathFor(romPath string) string {
+	return savePathFor(romPath, "", ".cht")
+}
+
+// Loads the cheats at cheatPath, or if it's empty, the ROM's default cheat
+// file. It isn't an error for that one not to exist, in which case there
+// aren't any cheats.
+func LoadCheatsFor(romPath string, cheatPath string) ([]Cheat, error) {
+	if cheatPath == "" {
+		if romPath == "" {
+			return nil, nil
+		}
+
+		cheatPath = CheatPathFor(romPath)
+		if _, err := os.Stat(cheatPath); errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+	}
+
+	file, err := os.Open(cheatPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load cheats: %w", err)
+	}
+	defer file.Close()
+
+	return ParseCheats(file)
+}
+
+func SaveCheats(path string, cheats []Cheat) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to save cheats: %w", err)
+	}
+
+	if err := WriteCheats(file, cheats); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to save cheats: %w", err)
+	}
+
+	return file.Close()
+}
+
+// Patches a byte read from ROM with any Game Genie codes for its address
+func (c *Cartridge) applyGameGenie(address uint16, value byte) byte {
+	for _, cheat := range c.gameGenieCodes {
+		if cheat.Address == address && (!cheat.HasCompare || cheat.Compare == value) {
+			return cheat.Value
+		}
+	}
+
+	return value
+}
+
+// Writes the GameShark codes' values to RAM. Like the real thing, this happens
+// once a frame, at the start of VBlank. Bytes which already have the right
+// value are left alone, so battery backed RAM isn't marked as changed every
+// frame.
+func (gameboy *GameBoy) applyGameShark() {
+	for _, cheat := range gameboy.cheats {
+		if !cheat.Enabled || cheat.Format != CHEAT_GAMESHARK {
+			continue
+		}
+
+		if cheat.Bank >= 0 && Between(cheat.Address, EXTERNAL_RAM_START, EXTERNAL_RAM_END) {
+			if gameboy.cartridge.readRam(cheat.Bank, cheat.Address) != cheat.Value {
+				gameboy.cartridge.writeRam(cheat.Bank, cheat.Address, cheat.Value)
+			}
+		} else if gameboy.bus.peekByte(cheat.Address) != cheat.Value {
+			gameboy.bus.pokeByte(cheat.Address, cheat.Value)
+		}
+	}
+}
+
+// Returns a copy of the cheats, in the order they were added
+func (gameboy *GameBoy) Cheats() []Cheat {
+	return append([]Cheat{}, gameboy.cheats...)
+}
+
+func (gameboy *GameBoy) AddCheat(cheat Cheat) {
+	gameboy.cheats = append(gameboy.cheats, cheat)
+	gameboy.updateCheats()
+}
+
+// Turns the cheat at index (in Cheats) on or off
+func (gameboy *GameBoy) SetCheatEnabled(index int, enabled bool) error {
+	if index < 0 || index >= len(gameboy.cheats) {
+		return fmt.Errorf("no cheat %d", index)
+	}
+
+	gameboy.cheats[index].Enabled = enabled
+	gameboy.updateCheats()
+
+	return nil
+}
+
+func (gameboy *GameBoy) RemoveCheat(index int) error {
+	if index < 0 || index >= len(gameboy.cheats) {
+		return fmt.Errorf("no cheat %d", index)
+	}
+
+	gameboy.cheats = append(gameboy.cheats[:index], gameboy.cheats[index+1:]...)
+	gameboy.updateCheats()
+
+	return nil
+}
+
+// The cartridge only keeps the Game Genie codes which are on, so reads from
+// ROM don't have to check anything else
+func (gameboy *GameBoy) updateCheats() {
+	gameboy.cartridge.gameGenieCodes = nil
+
+	for _, cheat := range gameboy.cheats {
+		if cheat.Enabled && cheat.Format == CHEAT_GAME_GENIE {
+			gameboy.cartridge.gameGenieCodes = append(gameboy.cartridge.gameGenieCodes, cheat)
+		}
+	}
+}
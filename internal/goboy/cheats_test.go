@@ -0,0 +1,198 @@
+package goboy
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseCheat(t *testing.T) {
+	tests := []struct {
+		code     string
+		expected Cheat
+	}{
+		{"00a-17b", Cheat{Code: "00A-17B", Format: CHEAT_GAME_GENIE, Address: 0x4A17, Value: 0x00, Bank: -1}},
+		{"00A-17B-C49", Cheat{Code: "00A-17B-C49", Format: CHEAT_GAME_GENIE, Address: 0x4A17, Value: 0x00, Compare: 0xC8, HasCompare: true, Bank: -1}},
+		{"990-00B-E0E", Cheat{Code: "990-00B-E0E", Format: CHEAT_GAME_GENIE, Address: 0x4000, Value: 0x99, Compare: 0x01, HasCompare: true, Bank: -1}},
+		{"01FF16D0", Cheat{Code: "01FF16D0", Format: CHEAT_GAMESHARK, Address: 0xD016, Value: 0xFF, Bank: -1}},
+		{"817700A0", Cheat{Code: "817700A0", Format: CHEAT_GAMESHARK, Address: 0xA000, Value: 0x77, Bank: 1}},
+	}
+
+	for _, test := range tests {
+		test.expected.Enabled = true
+
+		cheat, err := ParseCheat(test.code)
+		if err != nil {
+			t.Errorf("%s: %v", test.code, err)
+			continue
+		}
+
+		if cheat != test.expected {
+			t.Errorf("%s: expected %+v, got %+v", test.code, test.expected, cheat)
+		}
+	}
+}
+
+func TestParseCheat_Invalid(t *testing.T) {
+	codes := map[string]string{
+		"00A-17":       "expected ABC-DEF",
+		"00G-17B":      "hex digits",
+		"00A-170":      "patch ROM",
+		"02FF16D0":     "unsupported GameShark code type 02",
+		"01FF0040":     "write to RAM",
+		"00A-17B-C49-": "expected ABC-DEF",
+	}
+
+	for code, expected := range codes {
+		_, err := ParseCheat(code)
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("%s: expected an error containing %q, got %v", code, expected, err)
+		}
+	}
+}
+
+func newCheatTestGameBoy(t *testing.T) *GameBoy {
+	// MBC1 with 4 banks of battery backed RAM
+	options := DefaultOptions()
+	options.RomPath = writeTestRom(t, 0x03, 0x02, 0x03)
+
+	gameboy, err := NewGameBoy(options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return gameboy
+}
+
+func TestCheats_GameGenie(t *testing.T) {
+	gameboy := newCheatTestGameBoy(t)
+
+	// Each bank of the test ROM is filled with its number, and this code only
+	// patches 0x4000 in bank 1
+	cheat, _ := ParseCheat("990-00B-E0E")
+	gameboy.AddCheat(cheat)
+
+	if value := gameboy.ReadMemory(0x4000); value != 0x99 {
+		t.Errorf("expected bank 1 to be patched to 0x99, got 0x%2.2X", value)
+	}
+	if value := gameboy.ReadMemory(0x4001); value != 0x01 {
+		t.Errorf("expected the next byte to be left alone, got 0x%2.2X", value)
+	}
+
+	gameboy.WriteMemory(0x2000, 0x02)
+	if value := gameboy.ReadMemory(0x4000); value != 0x02 {
+		t.Errorf("expected bank 2 not to match the compare value, got 0x%2.2X", value)
+	}
+
+	gameboy.WriteMemory(0x2000, 0x01)
+	gameboy.SetCheatEnabled(0, false)
+	if value := gameboy.ReadMemory(0x4000); value != 0x01 {
+		t.Errorf("expected turning the cheat off to unpatch ROM, got 0x%2.2X", value)
+	}
+}
+
+func TestCheats_GameShark(t *testing.T) {
+	gameboy := newCheatTestGameBoy(t)
+
+	wram, _ := ParseCheat("014200C1")
+	sram, _ := ParseCheat("817700A0")
+	gameboy.AddCheat(wram)
+	gameboy.AddCheat(sram)
+
+	target := gameboy.FrameCount() + 1
+	for gameboy.FrameCount() != target {
+		if value := gameboy.ReadMemory(0xC100); value == 0x42 {
+			t.Fatal("expected GameShark codes to wait for VBlank")
+		}
+		gameboy.Step()
+	}
+
+	if value := gameboy.ReadMemory(0xC100); value != 0x42 {
+		t.Errorf("expected 0xC100 to be 0x42, got 0x%2.2X", value)
+	}
+
+	// Written straight to the bank, even though RAM is disabled and bank 0 is
+	// mapped
+	if value := gameboy.cartridge.ramBanks[1].data[0]; value != 0x77 {
+		t.Errorf("expected bank 1 of cartridge RAM to be 0x77, got 0x%2.2X", value)
+	}
+	if value := gameboy.cartridge.ramBanks[0].data[0]; value != 0x00 {
+		t.Errorf("expected bank 0 of cartridge RAM to be left alone, got 0x%2.2X", value)
+	}
+
+	gameboy.RemoveCheat(0)
+	gameboy.WriteMemory(0xC100, 0x00)
+	target = gameboy.FrameCount() + 1
+	for gameboy.FrameCount() != target {
+		gameboy.Step()
+	}
+
+	if value := gameboy.ReadMemory(0xC100); value != 0x00 {
+		t.Errorf("expected removed cheats to stop being applied, got 0x%2.2X", value)
+	}
+}
+
+func TestCheats_File(t *testing.T) {
+	text := "# code on|off description\n" +
+		"00A-17B-C49 on Infinite lives\n" +
+		"\n" +
+		"01FF16D0 off\n"
+
+	cheats, err := ParseCheats(strings.NewReader(text))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(cheats) != 2 || !cheats[0].Enabled || cheats[0].Description != "Infinite lives" || cheats[1].Enabled {
+		t.Fatalf("unexpected cheats %+v", cheats)
+	}
+
+	var out strings.Builder
+	if err := WriteCheats(&out, cheats); err != nil {
+		t.Fatal(err)
+	}
+
+	if out.String() != strings.ReplaceAll(text, "\n\n", "\n") {
+		t.Errorf("expected the file to round trip, got:\n%s", out.String())
+	}
+
+	_, err = ParseCheats(strings.NewReader("00A-17B-C49 on\n00A-17B maybe\n"))
+	if err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("expected an error on line 2, got %v", err)
+	}
+}
+
+func TestLoadCheatsFor_Options(t *testing.T) {
+	options := DefaultOptions()
+	options.RomPath = writeTestRom(t, 0x03, 0x02, 0x03)
+
+	// The ROM's own cheat file is picked up, and codes from the options added
+	// after it
+	err := os.WriteFile(CheatPathFor(options.RomPath), []byte("00A-17B-C49 off Lives\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	options.Cheats = []string{"01FF16D0"}
+
+	gameboy, err := NewGameBoy(options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cheats := gameboy.Cheats()
+	if len(cheats) != 2 || cheats[0].Code != "00A-17B-C49" || cheats[1].Code != "01FF16D0" {
+		t.Errorf("unexpected cheats %+v", cheats)
+	}
+
+	options.CheatPath = filepath.Join(t.TempDir(), "missing.cht")
+	if _, err := NewGameBoy(options); err == nil {
+		t.Error("expected an error for a cheat file that doesn't exist")
+	}
+
+	options.CheatPath = ""
+	options.Cheats = []string{"nonsense"}
+	if _, err := NewGameBoy(options); err == nil {
+		t.Error("expected an error for an invalid code")
+	}
+}
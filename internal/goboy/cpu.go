@@ -12,6 +12,42 @@ type CPU struct {
 	halted                  bool
 	interruptMasterEnabled  bool
 	enablingInterruptMaster bool
+
+	// Set when HALT runs with IME off while an interrupt is already pending.
+	// The CPU doesn't halt, but fails to increment PC after the next read, so
+	// the byte after HALT is read twice.
+	// @see https://gbdev.io/pandocs/halt.html#halt-bug
+	haltBug bool
+	// Set by STOP. Nothing runs, not even the timer or PPU, until a button is
+	// pressed on one of the joypad lines the game has selected.
+	stopped bool
+	// Set after running an invalid opcode. The CPU does nothing until reset.
+	lockedUp bool
+
+	// The opcode being run and where it was fetched from. PC can't be used to
+	// work this out, as the halt bug stops it moving past the opcode.
+	opcode        byte
+	opcodeAddress uint16
+
+	// Called once per M-cycle, with the read or write the CPU made on the bus
+	// during it, or nil if it didn't touch the bus. Reads the emulator makes on
+	// the CPU's behalf, like checking for interrupts, aren't included.
+	onMCycle func(access *busAccess)
+}
+
+type busAccess struct {
+	address uint16
+	value   byte
+	write   bool
+}
+
+func (access busAccess) String() string {
+	kind := "read"
+	if access.write {
+		kind = "write"
+	}
+
+	return fmt.Sprintf("%s 0x%02X at 0x%04X", kind, access.value, access.address)
 }
 
 func NewCPU(gameboy *GameBoy, bus MemoryBusser) *CPU {
@@ -26,16 +62,40 @@ func NewCPU(gameboy *GameBoy, bus MemoryBusser) *CPU {
 }
 
 func (cpu *CPU) Tick() {
+	if cpu.lockedUp {
+		cpu.idle()
+		return
+	}
+
+	if cpu.stopped {
+		if cpu.gameboy.joypad.readByte(IO_JOYP)&0x0F == 0x0F {
+			return
+		}
+
+		cpu.stopped = false
+	}
+
 	if cpu.halted {
-		cpu.gameboy.Cycle(1)
-		if cpu.bus.readByte(IO_IF) != 0 {
+		cpu.idle()
+
+		// Any enabled interrupt wakes the CPU, even if IME is off, in which
+		// case it carries on without handling it
+		if cpu.pendingInterrupts() != 0 {
 			cpu.halted = false
 		}
 	} else {
-		currentOpcode := cpu.fetchNextOpcode()
-		instruction := fetchInstruction(currentOpcode)
+		if cpu.gameboy.tracer != nil {
+			cpu.gameboy.tracer.trace(cpu.gameboy)
+		}
+
+		if pc := cpu.registers.pc; cpu.gameboy.hooks.watching(HOOK_EXECUTE, pc) {
+			cpu.gameboy.hooks.fire(HOOK_EXECUTE, pc, cpu.bus.peekByte(pc), cpu.gameboy.cartridge)
+		}
+
+		cpu.opcodeAddress = cpu.registers.pc
+		cpu.opcode = cpu.fetchNextOpcode()
+		instruction := fetchInstruction(cpu.opcode)
 		instruction(cpu)
-		cpu.debugPrint()
 	}
 
 	if cpu.interruptMasterEnabled {
@@ -50,36 +110,78 @@ func (cpu *CPU) Tick() {
 
 func (cpu *CPU) fetchNextOpcode() byte {
 	pc := cpu.registers.read(R_PC)
-	opcode := cpu.bus.readByte(pc)
-	cpu.registers.write(R_PC, pc+1)
+	opcode := cpu.readByte(pc)
+
+	if cpu.haltBug {
+		cpu.haltBug = false
+	} else {
+		cpu.registers.write(R_PC, pc+1)
+	}
 
 	return opcode
 }
 
-func (cpu *CPU) debugPrint() {
-	if !DEBUG {
-		return
+// Every bus access takes an M-cycle. The access happens at the start of it,
+// after the rest of the machine has caught up with all the previous cycles, so
+// the PPU, timer and DMA see memory change at the right time.
+func (cpu *CPU) readByte(address uint16) byte {
+	value := cpu.bus.readByte(address)
+
+	if cpu.onMCycle != nil {
+		cpu.onMCycle(&busAccess{address: address, value: value})
+	}
+
+	cpu.gameboy.Cycle(1)
+	return value
+}
+
+func (cpu *CPU) writeByte(address uint16, value byte) {
+	cpu.bus.writeByte(address, value)
+
+	if cpu.onMCycle != nil {
+		cpu.onMCycle(&busAccess{address: address, value: value, write: true})
+	}
+
+	cpu.gameboy.Cycle(1)
+}
+
+// An M-cycle spent on something internal, like 16 bit arithmetic, which
+// doesn't touch the bus
+func (cpu *CPU) idle() {
+	if cpu.onMCycle != nil {
+		cpu.onMCycle(nil)
 	}
 
+	cpu.gameboy.Cycle(1)
+}
+
+func (cpu *CPU) saveState(s *stateWriter) {
+	r := cpu.registers
+	s.write(r.a, r.f, r.b, r.c, r.d, r.e, r.h, r.l, r.sp, r.pc)
+	s.write(cpu.halted, cpu.interruptMasterEnabled, cpu.enablingInterruptMaster)
+	s.write(cpu.haltBug, cpu.stopped, cpu.lockedUp)
+}
+
+func (cpu *CPU) loadState(s *stateReader) {
 	r := cpu.registers
+	s.read(&r.a, &r.f, &r.b, &r.c, &r.d, &r.e, &r.h, &r.l, &r.sp, &r.pc)
+	s.read(&cpu.halted, &cpu.interruptMasterEnabled, &cpu.enablingInterruptMaster)
 
-	out := fmt.Sprintf(
-		"A:%2.2X F:%2.2X B:%2.2X C:%2.2X D:%2.2X E:%2.2X H:%2.2X L:%2.2X SP:%4.4X PC:%4.4X PCMEM:%2.2X,%2.2X,%2.2X,%2.2X\n",
-		r.a,
-		r.f,
-		r.b,
-		r.c,
-		r.d,
-		r.e,
-		r.h,
-		r.l,
-		r.sp,
-		r.pc,
-		cpu.bus.readByte(r.pc),
-		cpu.bus.readByte(r.pc+1),
-		cpu.bus.readByte(r.pc+2),
-		cpu.bus.readByte(r.pc+3),
-	)
-	// fmt.Print(out)
-	GetInstance().WriteString(out)
+	// The halt bug was added in version 5
+	cpu.haltBug = false
+	if s.version >= 5 {
+		s.read(&cpu.haltBug)
+	}
+
+	// STOP was added in version 6
+	cpu.stopped = false
+	if s.version >= 6 {
+		s.read(&cpu.stopped)
+	}
+
+	// Lockups were added in version 7
+	cpu.lockedUp = false
+	if s.version >= 7 {
+		s.read(&cpu.lockedUp)
+	}
 }
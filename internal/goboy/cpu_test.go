@@ -0,0 +1,26 @@
+package goboy
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestCpu_EachAccessTakesItsOwnMCycle(t *testing.T) {
+	// CALL 0xC010
+	gameboy := newWramTestGameBoy(t, []byte{0xCD, 0x10, 0xC0}, false)
+
+	// The rest of the machine should have caught up with every earlier cycle
+	// by the time each access happens
+	var cycles []uint64
+	gameboy.cpu.onMCycle = func(_ *busAccess) {
+		cycles = append(cycles, gameboy.cycles)
+	}
+
+	start := gameboy.cycles
+	gameboy.Step()
+
+	expected := []uint64{start, start + 1, start + 2, start + 3, start + 4, start + 5}
+	if !slices.Equal(cycles, expected) {
+		t.Errorf("expected accesses on M-cycles %v, got %v", expected, cycles)
+	}
+}
@@ -0,0 +1,717 @@
+package goboy
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/seashairo/goboy/internal/disasm"
+)
+
+const (
+	DEBUGGER_PROMPT = "(goboy) "
+	// How many of the instructions run before PC are shown by dis
+	DEBUGGER_HISTORY_LENGTH = 4
+	// Defaults for dis and x when no length is given
+	DEBUGGER_DISASSEMBLY_LENGTH = 8
+	DEBUGGER_DUMP_LENGTH        = 64
+)
+
+// An interactive command line debugger. Commands are read a line at a time and
+// the emulator runs on the calling goroutine in between, so nothing else should
+// be driving the GameBoy at the same time.
+type Debugger struct {
+	gameboy *GameBoy
+	out     io.Writer
+
+	breakpoints []Breakpoint
+	watchpoints []Watchpoint
+	// The memory hooks checking the watchpoints
+	watchpointHooks []MemoryHookId
+
+	// Set by a watchpoint part way through an instruction, so the debugger can
+	// stop once the instruction has finished
+	watchpointHit string
+	lockup        *LockupFault
+	interrupted   atomic.Bool
+
+	// The PCs of the last few instructions run, oldest first
+	history     []uint16
+	lastCommand string
+}
+
+// Stops the debugger when PC reaches Address. Bank is the ROM bank that has to
+// be mapped there, or -1 to stop whatever is mapped.
+type Breakpoint struct {
+	Bank    int
+	Address uint16
+}
+
+// Stops the debugger after an instruction reads or writes Address
+type Watchpoint struct {
+	Address uint16
+	// How many bytes from Address are watched
+	Length int
+	Read   bool
+	Write  bool
+}
+
+type debuggerCommand struct {
+	names       []string
+	usage       string
+	description string
+	run         func(debugger *Debugger, args []string) error
+}
+
+var DEBUGGER_COMMANDS []debuggerCommand
+
+func init() {
+	DEBUGGER_COMMANDS = []debuggerCommand{
+		{[]string{"step", "s"}, "step [n]", "run n instructions (default 1)", (*Debugger).step},
+		{[]string{"next", "n"}, "next", "run one instruction, stepping over calls", (*Debugger).next},
+		{[]string{"continue", "c"}, "continue", "run until a breakpoint or watchpoint is hit", (*Debugger).cont},
+		{[]string{"frame", "f"}, "frame [n]", "run until n more frames have been drawn (default 1)", (*Debugger).frame},
+		{[]string{"line"}, "line <ly>", "run until the PPU starts scanline ly", (*Debugger).line},
+		{[]string{"break", "b"}, "break [[bank:]addr|symbol]", "add a breakpoint, or list them", (*Debugger).addBreakpoint},
+		{[]string{"watch", "w"}, "watch [r|w|rw] [addr]", "add a watchpoint on bus reads and/or writes, or list them", (*Debugger).addWatchpoint},
+		{[]string{"delete", "d"}, "delete <n>", "remove breakpoint n", (*Debugger).deleteBreakpoint},
+		{[]string{"unwatch"}, "unwatch <n>", "remove watchpoint n", (*Debugger).deleteWatchpoint},
+		{[]string{"regs", "r"}, "regs", "show the CPU registers", (*Debugger).regs},
+		{[]string{"x"}, "x <addr> [len]", "dump memory (default 64 bytes)", (*Debugger).dump},
+		{[]string{"dis", "l"}, "dis [addr] [n]", "disassemble n instructions from addr (default around PC)", (*Debugger).dis},
+		{[]string{"reset"}, "reset", "reset the Game Boy", (*Debugger).reset},
+		{[]string{"help", "h", "?"}, "help", "show this list", (*Debugger).help},
+		{[]string{"quit", "q"}, "quit", "exit the debugger", nil},
+	}
+}
+
+func NewDebugger(gameboy *GameBoy, out io.Writer) *Debugger {
+	debugger := &Debugger{
+		gameboy: gameboy,
+		out:     out,
+	}
+
+	gameboy.RegisterLockupCallback(func(fault LockupFault) {
+		debugger.lockup = &fault
+	})
+
+	return debugger
+}
+
+// Reads and runs commands until in runs out or the quit command is run
+func (debugger *Debugger) Run(in io.Reader) {
+	scanner := bufio.NewScanner(in)
+
+	debugger.printLocation()
+
+	for {
+		fmt.Fprint(debugger.out, DEBUGGER_PROMPT)
+		if !scanner.Scan() {
+			fmt.Fprintln(debugger.out)
+			return
+		}
+
+		if debugger.Execute(scanner.Text()) {
+			return
+		}
+	}
+}
+
+// Stops whatever the debugger is running at the end of the current
+// instruction. Safe to call from other goroutines, e.g. on Ctrl-C.
+func (debugger *Debugger) Interrupt() {
+	debugger.interrupted.Store(true)
+}
+
+// Flushes battery backed RAM and the trace, as the GameBoy would when stopping
+func (debugger *Debugger) Close() error {
+	return debugger.gameboy.Close()
+}
+
+// Runs a single command line, and returns true if it was quit. An empty line
+// repeats the last command.
+func (debugger *Debugger) Execute(line string) bool {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		if debugger.lastCommand == "" {
+			return false
+		}
+		fields = strings.Fields(debugger.lastCommand)
+	}
+	debugger.lastCommand = strings.Join(fields, " ")
+
+	for _, command := range DEBUGGER_COMMANDS {
+		if !slices.Contains(command.names, fields[0]) {
+			continue
+		}
+
+		if command.run == nil {
+			return true
+		}
+
+		if err := command.run(debugger, fields[1:]); err != nil {
+			fmt.Fprintf(debugger.out, "error: %v\n", err)
+		}
+		return false
+	}
+
+	fmt.Fprintf(debugger.out, "error: unknown command %q, try help\n", fields[0])
+	return false
+}
+
+// Runs instructions until done returns true, or something else stops it
+func (debugger *Debugger) runUntil(done func() bool) {
+	gameboy := debugger.gameboy
+	debugger.interrupted.Store(false)
+	debugger.watchpointHit = ""
+	debugger.lockup = nil
+
+	if gameboy.LockedUp() {
+		fmt.Fprintln(debugger.out, "The CPU is locked up, reset to carry on")
+		return
+	}
+
+	for {
+		debugger.stepInstruction()
+
+		pc := gameboy.cpu.registers.read(R_PC)
+
+		switch {
+		case debugger.watchpointHit != "":
+			fmt.Fprintln(debugger.out, debugger.watchpointHit)
+		case debugger.lockup != nil:
+			fmt.Fprintln(debugger.out, debugger.lockup.Error())
+		case gameboy.InStopMode():
+			fmt.Fprintln(debugger.out, "Stopped, waiting for a button press")
+		case done():
+		case debugger.breakpointAt(pc) >= 0:
+			fmt.Fprintf(debugger.out, "Breakpoint %d\n", debugger.breakpointAt(pc)+1)
+		case debugger.interrupted.Load():
+			fmt.Fprintln(debugger.out, "Interrupted")
+		default:
+			continue
+		}
+
+		debugger.printLocation()
+		return
+	}
+}
+
+func (debugger *Debugger) stepInstruction() {
+	pc := debugger.gameboy.cpu.registers.read(R_PC)
+
+	// Halted cycles don't run anything new
+	if len(debugger.history) == 0 || debugger.history[len(debugger.history)-1] != pc {
+		debugger.history = append(debugger.history, pc)
+		if len(debugger.history) > DEBUGGER_HISTORY_LENGTH {
+			debugger.history = debugger.history[1:]
+		}
+	}
+
+	debugger.gameboy.Step()
+}
+
+func (debugger *Debugger) checkWatchpoints(access MemoryAccess) {
+	if debugger.watchpointHit != "" {
+		return
+	}
+
+	if i := findWatchpoint(debugger.watchpoints, access); i >= 0 {
+		debugger.watchpointHit = fmt.Sprintf("Watchpoint %d: %s", i+1, access)
+	}
+}
+
+func (debugger *Debugger) breakpointAt(address uint16) int {
+	return findBreakpoint(debugger.breakpoints, debugger.gameboy.cartridge, address)
+}
+
+// Returns the index of the breakpoint at the given address, or -1 if there
+// isn't one for the bank that's mapped there
+func findBreakpoint(breakpoints []Breakpoint, cartridge *Cartridge, address uint16) int {
+	bank := cartridge.romBankAt(address)
+
+	for i, breakpoint := range breakpoints {
+		if breakpoint.Address == address && (breakpoint.Bank < 0 || breakpoint.Bank == bank) {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// Returns the index of the first watchpoint the access triggers, or -1
+func findWatchpoint(watchpoints []Watchpoint, access MemoryAccess) int {
+	for i, watchpoint := range watchpoints {
+		if watchpoint.covers(access.Address) && watchpoint.hookKind()&access.Kind != 0 {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func (watchpoint Watchpoint) covers(address uint16) bool {
+	return address >= watchpoint.Address && int(address) < int(watchpoint.Address)+watchpoint.Length
+}
+
+func (watchpoint Watchpoint) hookKind() MemoryHookKind {
+	var kind MemoryHookKind
+	if watchpoint.Read {
+		kind |= HOOK_READ
+	}
+	if watchpoint.Write {
+		kind |= HOOK_WRITE
+	}
+
+	return kind
+}
+
+// Swaps the hooks for a list of watchpoints for new ones after it changes, and
+// returns their IDs
+func hookWatchpoints(
+	gameboy *GameBoy,
+	hooks []MemoryHookId,
+	watchpoints []Watchpoint,
+	callback func(access MemoryAccess),
+) []MemoryHookId {
+	for _, id := range hooks {
+		gameboy.RemoveMemoryHook(id)
+	}
+
+	hooks = hooks[:0]
+	for _, watchpoint := range watchpoints {
+		hooks = append(hooks, gameboy.AddMemoryHook(MemoryHook{
+			Kind:     watchpoint.hookKind(),
+			Start:    watchpoint.Address,
+			End:      uint16(int(watchpoint.Address) + watchpoint.Length - 1),
+			Bank:     -1,
+			Callback: callback,
+		}))
+	}
+
+	return hooks
+}
+
+func (debugger *Debugger) hookWatchpoints() {
+	debugger.watchpointHooks = hookWatchpoints(
+		debugger.gameboy, debugger.watchpointHooks, debugger.watchpoints, debugger.checkWatchpoints,
+	)
+}
+
+func (debugger *Debugger) step(args []string) error {
+	count, err := parseCount(args, 1)
+	if err != nil {
+		return err
+	}
+
+	steps := 0
+	debugger.runUntil(func() bool {
+		steps++
+		return steps >= count
+	})
+
+	return nil
+}
+
+func (debugger *Debugger) next(args []string) error {
+	registers := debugger.gameboy.cpu.registers
+	pc := registers.read(R_PC)
+	sp := registers.read(R_SP)
+	instruction := disasm.Disassemble(debugger.gameboy.bus.peekByte, pc)
+
+	if !instruction.IsCall() {
+		return debugger.step(nil)
+	}
+
+	// Run until the call returns, which is when we're back after it with the
+	// return address popped off the stack again
+	returnAddress := pc + uint16(instruction.Length())
+	debugger.runUntil(func() bool {
+		return registers.read(R_PC) == returnAddress && registers.read(R_SP) == sp
+	})
+
+	return nil
+}
+
+func (debugger *Debugger) cont(args []string) error {
+	debugger.runUntil(func() bool { return false })
+	return nil
+}
+
+func (debugger *Debugger) frame(args []string) error {
+	count, err := parseCount(args, 1)
+	if err != nil {
+		return err
+	}
+
+	target := debugger.gameboy.FrameCount() + uint32(count)
+	debugger.runUntil(func() bool {
+		return debugger.gameboy.FrameCount() >= target
+	})
+
+	return nil
+}
+
+func (debugger *Debugger) line(args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: line <ly>")
+	}
+
+	ly, err := strconv.ParseUint(args[0], 10, 8)
+	if err != nil || ly >= 154 {
+		return fmt.Errorf("invalid scanline %q", args[0])
+	}
+
+	// Wait for LY to change to the line, so this doesn't stop straight away
+	// when it's already there
+	previous := debugger.gameboy.bus.peekByte(LCD_LY)
+	debugger.runUntil(func() bool {
+		current := debugger.gameboy.bus.peekByte(LCD_LY)
+		reached := current == byte(ly) && previous != byte(ly)
+		previous = current
+		return reached
+	})
+
+	return nil
+}
+
+func (debugger *Debugger) addBreakpoint(args []string) error {
+	if len(args) == 0 {
+		if len(debugger.breakpoints) == 0 {
+			fmt.Fprintln(debugger.out, "No breakpoints")
+		}
+		for i, breakpoint := range debugger.breakpoints {
+			location := fmt.Sprintf("%4.4X", breakpoint.Address)
+			if breakpoint.Bank >= 0 {
+				location = fmt.Sprintf("%2.2X:%4.4X", breakpoint.Bank, breakpoint.Address)
+			}
+			fmt.Fprintf(debugger.out, "%d: %s\n", i+1, location)
+		}
+		return nil
+	}
+
+	bank, address, err := debugger.parseLocation(args[0])
+	if err != nil {
+		return err
+	}
+
+	debugger.breakpoints = append(debugger.breakpoints, Breakpoint{Bank: bank, Address: address})
+	fmt.Fprintf(debugger.out, "Breakpoint %d at %s\n", len(debugger.breakpoints), args[0])
+
+	return nil
+}
+
+func (debugger *Debugger) deleteBreakpoint(args []string) error {
+	index, err := parseIndex(args, len(debugger.breakpoints), "breakpoint")
+	if err != nil {
+		return err
+	}
+
+	debugger.breakpoints = slices.Delete(debugger.breakpoints, index, index+1)
+	return nil
+}
+
+func (debugger *Debugger) addWatchpoint(args []string) error {
+	if len(args) == 0 {
+		if len(debugger.watchpoints) == 0 {
+			fmt.Fprintln(debugger.out, "No watchpoints")
+		}
+		for i, watchpoint := range debugger.watchpoints {
+			fmt.Fprintf(debugger.out, "%d: %s %4.4X\n", i+1, watchpoint.kind(), watchpoint.Address)
+		}
+		return nil
+	}
+
+	watchpoint := Watchpoint{Length: 1, Read: true, Write: true}
+	if len(args) == 2 {
+		switch args[0] {
+		case "r":
+			watchpoint.Write = false
+		case "w":
+			watchpoint.Read = false
+		case "rw":
+		default:
+			return fmt.Errorf("unknown watchpoint kind %q, expected r, w or rw", args[0])
+		}
+		args = args[1:]
+	}
+
+	bank, address, err := debugger.parseLocation(args[0])
+	if err != nil {
+		return err
+	}
+	if bank >= 0 {
+		return errors.New("watchpoints are on bus addresses, so can't have a bank")
+	}
+
+	watchpoint.Address = address
+	debugger.watchpoints = append(debugger.watchpoints, watchpoint)
+	debugger.hookWatchpoints()
+	fmt.Fprintf(debugger.out, "Watchpoint %d: %s %4.4X\n", len(debugger.watchpoints), watchpoint.kind(), address)
+
+	return nil
+}
+
+func (watchpoint Watchpoint) kind() string {
+	switch {
+	case watchpoint.Read && watchpoint.Write:
+		return "rw"
+	case watchpoint.Read:
+		return "r"
+	default:
+		return "w"
+	}
+}
+
+func (debugger *Debugger) deleteWatchpoint(args []string) error {
+	index, err := parseIndex(args, len(debugger.watchpoints), "watchpoint")
+	if err != nil {
+		return err
+	}
+
+	debugger.watchpoints = slices.Delete(debugger.watchpoints, index, index+1)
+	debugger.hookWatchpoints()
+	return nil
+}
+
+func (debugger *Debugger) regs(args []string) error {
+	cpu := debugger.gameboy.cpu
+	registers := cpu.registers
+
+	flags := []byte("----")
+	for i, flag := range []CpuFlag{FLAG_Z, FLAG_N, FLAG_H, FLAG_C} {
+		if registers.readFlag(flag) {
+			flags[i] = "ZNHC"[i]
+		}
+	}
+
+	fmt.Fprintf(
+		debugger.out,
+		"A:%2.2X F:%2.2X [%s] BC:%4.4X DE:%4.4X HL:%4.4X SP:%4.4X PC:%4.4X\n",
+		registers.read(R_A), registers.read(R_F), flags,
+		registers.read(R_BC), registers.read(R_DE), registers.read(R_HL),
+		registers.read(R_SP), registers.read(R_PC),
+	)
+
+	state := []string{}
+	if cpu.halted {
+		state = append(state, "halted")
+	}
+	if cpu.stopped {
+		state = append(state, "stopped")
+	}
+	if cpu.lockedUp {
+		state = append(state, "locked up")
+	}
+
+	fmt.Fprintf(
+		debugger.out,
+		"IME:%d IE:%2.2X IF:%2.2X LY:%d frame:%d %s\n",
+		boolToInt(cpu.interruptMasterEnabled),
+		debugger.gameboy.bus.peekByte(INTERRUPT_ENABLE_REGISTER_START),
+		debugger.gameboy.bus.peekByte(IO_IF),
+		debugger.gameboy.bus.peekByte(LCD_LY),
+		debugger.gameboy.FrameCount(),
+		strings.Join(state, " "),
+	)
+
+	return nil
+}
+
+func (debugger *Debugger) dump(args []string) error {
+	if len(args) == 0 || len(args) > 2 {
+		return errors.New("usage: x <addr> [len]")
+	}
+
+	_, address, err := debugger.parseLocation(args[0])
+	if err != nil {
+		return err
+	}
+
+	length, err := parseCount(args[1:], DEBUGGER_DUMP_LENGTH)
+	if err != nil {
+		return err
+	}
+
+	data := make([]byte, min(length, 0x10000-int(address)))
+	for i := range data {
+		data[i] = debugger.gameboy.bus.peekByte(address + uint16(i))
+	}
+
+	fmt.Fprint(debugger.out, formatHexdump(address, data))
+	return nil
+}
+
+func (debugger *Debugger) dis(args []string) error {
+	pc := debugger.gameboy.cpu.registers.read(R_PC)
+	start := pc
+	addresses := []uint16{}
+
+	if len(args) > 0 {
+		_, address, err := debugger.parseLocation(args[0])
+		if err != nil {
+			return err
+		}
+		start = address
+	} else {
+		// Show how we got here as well
+		addresses = slices.Clone(debugger.history)
+	}
+
+	count, err := parseCount(args[min(len(args), 1):], DEBUGGER_DISASSEMBLY_LENGTH)
+	if err != nil {
+		return err
+	}
+
+	address := start
+	for i := 0; i < count; i++ {
+		addresses = append(addresses, address)
+		address += uint16(disasm.Disassemble(debugger.gameboy.bus.peekByte, address).Length())
+	}
+
+	for _, address := range addresses {
+		fmt.Fprintln(debugger.out, debugger.formatInstruction(address, address == pc))
+	}
+
+	return nil
+}
+
+func (debugger *Debugger) reset(args []string) error {
+	debugger.gameboy.Reset()
+	debugger.history = nil
+	debugger.printLocation()
+
+	return nil
+}
+
+func (debugger *Debugger) help(args []string) error {
+	for _, command := range DEBUGGER_COMMANDS {
+		aliases := ""
+		if len(command.names) > 1 {
+			aliases = " (" + strings.Join(command.names[1:], ", ") + ")"
+		}
+		fmt.Fprintf(debugger.out, "  %-28s %s%s\n", command.usage, command.description, aliases)
+	}
+
+	fmt.Fprintln(debugger.out, "Addresses are hex, with an optional bank for breakpoints, e.g. 01:4000, or symbols.")
+	fmt.Fprintln(debugger.out, "An empty line repeats the last command.")
+
+	return nil
+}
+
+func (debugger *Debugger) printLocation() {
+	pc := debugger.gameboy.cpu.registers.read(R_PC)
+	fmt.Fprintln(debugger.out, debugger.formatInstruction(pc, true))
+}
+
+func (debugger *Debugger) formatInstruction(address uint16, current bool) string {
+	instruction := disasm.Disassemble(debugger.gameboy.bus.peekByte, address)
+
+	bytes := make([]string, instruction.Length())
+	for i, value := range instruction.Bytes {
+		bytes[i] = fmt.Sprintf("%2.2X", value)
+	}
+
+	bank := debugger.gameboy.cartridge.romBankAt(address)
+	location := fmt.Sprintf("   %4.4X", address)
+	if bank >= 0 {
+		location = fmt.Sprintf("%2.2X:%4.4X", bank, address)
+	}
+
+	marker := "  "
+	if current {
+		marker = "=>"
+	}
+
+	symbols := debugger.gameboy.symbols
+	text := fmt.Sprintf("%s %s  %-8s  %s", marker, location, strings.Join(bytes, " "), symbols.Annotate(instruction, bank))
+
+	if label, ok := symbols.At(bank, address); ok {
+		text = label + ":\n" + text
+	}
+
+	return text
+}
+
+// Like parseAddress, but also takes the name of a symbol
+func (debugger *Debugger) parseLocation(text string) (int, uint16, error) {
+	symbol, ok := debugger.gameboy.symbols.Lookup(text)
+	if !ok {
+		return parseAddress(text)
+	}
+
+	// Banks only mean something for ROM
+	if symbol.Address >= 2*disasm.ROM_BANK_SIZE {
+		return -1, symbol.Address, nil
+	}
+
+	return symbol.Bank, symbol.Address, nil
+}
+
+// Parses a hex address like 4000, $4000 or 0x4000, optionally prefixed with a
+// ROM bank like 01:4000. The bank is -1 if there isn't one.
+func parseAddress(text string) (int, uint16, error) {
+	bank := -1
+
+	if bankText, addressText, found := strings.Cut(text, ":"); found {
+		value, err := strconv.ParseUint(trimHexPrefix(bankText), 16, 16)
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid bank %q", bankText)
+		}
+		bank = int(value)
+		text = addressText
+	}
+
+	address, err := strconv.ParseUint(trimHexPrefix(text), 16, 16)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid address %q", text)
+	}
+
+	return bank, uint16(address), nil
+}
+
+func trimHexPrefix(text string) string {
+	text = strings.TrimPrefix(text, "$")
+	text = strings.TrimPrefix(text, "0x")
+	return strings.TrimPrefix(text, "0X")
+}
+
+// Parses an optional positive decimal count
+func parseCount(args []string, fallback int) (int, error) {
+	if len(args) == 0 {
+		return fallback, nil
+	}
+
+	count, err := strconv.Atoi(args[0])
+	if err != nil || count < 1 {
+		return 0, fmt.Errorf("invalid count %q", args[0])
+	}
+
+	return count, nil
+}
+
+// Parses a 1 based index into a list, as shown by break and watch
+func parseIndex(args []string, length int, kind string) (int, error) {
+	if len(args) != 1 {
+		return 0, fmt.Errorf("expected the number of the %s to delete", kind)
+	}
+
+	index, err := strconv.Atoi(args[0])
+	if err != nil || index < 1 || index > length {
+		return 0, fmt.Errorf("no %s %q", kind, args[0])
+	}
+
+	return index - 1, nil
+}
+
+func boolToInt(value bool) int {
+	if value {
+		return 1
+	}
+	return 0
+}
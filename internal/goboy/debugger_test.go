@@ -0,0 +1,126 @@
+package goboy
+
+import (
+	"strings"
+	"testing"
+)
+
+// CALL 0xC010; LD (0xC100), A; INC B; JR -2; ...; INC A; RET
+var DEBUGGER_TEST_PROGRAM = []byte{
+	0xCD, 0x10, 0xC0, 0xEA, 0x00, 0xC1, 0x04, 0x18, 0xFE, 0x00,
+	0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x3C, 0xC9,
+}
+
+func runDebuggerScript(t *testing.T, commands ...string) (*GameBoy, string) {
+	t.Helper()
+
+	gameboy := newWramTestGameBoy(t, DEBUGGER_TEST_PROGRAM, false)
+	out := &strings.Builder{}
+	debugger := NewDebugger(gameboy, out)
+	debugger.Run(strings.NewReader(strings.Join(commands, "\n")))
+
+	return gameboy, out.String()
+}
+
+func TestDebugger_NextStepsOverCalls(t *testing.T) {
+	gameboy, out := runDebuggerScript(t, "next")
+
+	if pc := gameboy.ReadRegister(R_PC); pc != 0xC003 {
+		t.Errorf("expected to stop after the call at 0xC003, got 0x%4.4X\n%s", pc, out)
+	}
+	if a := gameboy.ReadRegister(R_A); a != 1 {
+		t.Errorf("expected the call to have run, A is %d", a)
+	}
+}
+
+func TestDebugger_StepRepeatsOnEmptyLines(t *testing.T) {
+	gameboy, out := runDebuggerScript(t, "step", "", "")
+
+	if pc := gameboy.ReadRegister(R_PC); pc != 0xC011 {
+		t.Errorf("expected CALL and INC A to have run, PC is 0x%4.4X\n%s", pc, out)
+	}
+}
+
+func TestDebugger_Breakpoints(t *testing.T) {
+	gameboy, out := runDebuggerScript(t, "break $C006", "continue")
+
+	if pc := gameboy.ReadRegister(R_PC); pc != 0xC006 {
+		t.Errorf("expected to stop at the breakpoint, PC is 0x%4.4X\n%s", pc, out)
+	}
+	if !strings.Contains(out, "Breakpoint 1\n=>    C006  04        INC B") {
+		t.Errorf("expected the breakpoint to be reported, got\n%s", out)
+	}
+}
+
+func TestDebugger_BankedBreakpointsOnlyMatchTheirBank(t *testing.T) {
+	gameboy := newWramTestGameBoy(t, DEBUGGER_TEST_PROGRAM, false)
+	debugger := NewDebugger(gameboy, &strings.Builder{})
+	debugger.Execute("break 00:0150")
+	debugger.Execute("break 01:4000")
+	debugger.Execute("break C006")
+
+	if index := debugger.breakpointAt(0x0150); index != 0 {
+		t.Errorf("expected bank 0 breakpoint to match, got %d", index)
+	}
+	if index := debugger.breakpointAt(0x4000); index != 1 {
+		t.Errorf("expected bank 1 breakpoint to match, got %d", index)
+	}
+
+	// cpu_instrs is an MBC1 cartridge
+	gameboy.WriteMemory(0x2000, 0x02)
+	if index := debugger.breakpointAt(0x4000); index != -1 {
+		t.Errorf("expected bank 1 breakpoint not to match with bank 2 mapped, got %d", index)
+	}
+	if index := debugger.breakpointAt(0xC006); index != 2 {
+		t.Errorf("expected unbanked breakpoint to match, got %d", index)
+	}
+}
+
+func TestDebugger_WatchpointsStopAfterTheInstruction(t *testing.T) {
+	gameboy, out := runDebuggerScript(t, "watch w C100", "continue")
+
+	if pc := gameboy.ReadRegister(R_PC); pc != 0xC006 {
+		t.Errorf("expected to stop after the write, PC is 0x%4.4X\n%s", pc, out)
+	}
+	if !strings.Contains(out, "Watchpoint 1: write 0x01 at 0xC100") {
+		t.Errorf("expected the watchpoint to be reported, got\n%s", out)
+	}
+}
+
+func TestDebugger_Disassembly(t *testing.T) {
+	_, out := runDebuggerScript(t, "step 2", "dis")
+
+	expected := "" +
+		"      C000  CD 10 C0  CALL $C010\n" +
+		"      C010  3C        INC A\n" +
+		"=>    C011  C9        RET\n" +
+		"      C012  00        NOP\n"
+	if !strings.Contains(out, expected) {
+		t.Errorf("expected history before PC, got\n%s", out)
+	}
+}
+
+func TestDebugger_ParseAddress(t *testing.T) {
+	for _, test := range []struct {
+		text    string
+		bank    int
+		address uint16
+	}{
+		{"4000", -1, 0x4000},
+		{"$ff80", -1, 0xFF80},
+		{"0xC000", -1, 0xC000},
+		{"1F:4abc", 0x1F, 0x4ABC},
+		{"$02:$5000", 0x02, 0x5000},
+	} {
+		bank, address, err := parseAddress(test.text)
+		if err != nil || bank != test.bank || address != test.address {
+			t.Errorf("%q: expected %d, 0x%4.4X, got %d, 0x%4.4X, %v", test.text, test.bank, test.address, bank, address, err)
+		}
+	}
+
+	for _, text := range []string{"", "10000", "xyz", "1:2:3"} {
+		if _, _, err := parseAddress(text); err == nil {
+			t.Errorf("%q: expected an error", text)
+		}
+	}
+}
@@ -64,3 +64,11 @@ func (dma *DMA) Tick() {
 func (dma *DMA) Active() bool {
 	return dma.active
 }
+
+func (dma *DMA) saveState(s *stateWriter) {
+	s.write(dma.active, dma.delay, dma.addressHi, dma.byteIndex)
+}
+
+func (dma *DMA) loadState(s *stateReader) {
+	s.read(&dma.active, &dma.delay, &dma.addressHi, &dma.byteIndex)
+}
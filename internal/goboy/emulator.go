@@ -1,14 +1,33 @@
 package goboy
 
-func Emulate() {
-	gameboy := NewGameBoy()
-	go gameboy.Run()
-	defer gameboy.Stop()
+import (
+	"fmt"
+	"os"
+	"os/signal"
+)
 
-	ui := NewUI(gameboy)
-	defer ui.Destroy()
-
-	for ui.running {
-		ui.Update()
+// Without a window there's nothing to close, so the emulator runs until the
+// process is interrupted
+func EmulateHeadless(options Options) error {
+	gameboy, err := NewGameBoy(options)
+	if err != nil {
+		return err
 	}
+
+	gameboy.RegisterLockupCallback(func(fault LockupFault) {
+		fmt.Println(fault)
+	})
+
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, os.Interrupt)
+	defer signal.Stop(interrupts)
+
+	go func() {
+		<-interrupts
+		gameboy.Stop()
+	}()
+
+	gameboy.Run()
+
+	return nil
 }
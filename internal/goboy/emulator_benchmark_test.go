@@ -8,8 +8,23 @@ import (
 	"time"
 )
 
+// Any ROM will do for tests which don't care about what's being run
+const TEST_ROM_PATH = "./data/roms/blargg/cpu_instrs.gb"
+
+func newTestGameBoy(tb testing.TB) *GameBoy {
+	options := DefaultOptions()
+	options.RomPath = TEST_ROM_PATH
+
+	gameboy, err := NewGameBoy(options)
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	return gameboy
+}
+
 func BenchmarkEmulate(b *testing.B) {
-	gameboy := NewGameBoy()
+	gameboy := newTestGameBoy(b)
 	go gameboy.Run()
 
 	start := time.Now().UnixMilli()
@@ -11,9 +11,11 @@ type FPSTimer struct {
 	startTime      time.Time
 	rateLimit      time.Duration
 	frameStartTime time.Time
+	// Whether to print the rate once a second
+	verbose bool
 }
 
-func NewFPSTimer(name string, fpsLimit int) *FPSTimer {
+func NewFPSTimer(name string, fpsLimit int, verbose bool) *FPSTimer {
 	var frameDuration time.Duration
 	if fpsLimit > 0 {
 		frameDuration = time.Second / time.Duration(fpsLimit)
@@ -22,6 +24,7 @@ func NewFPSTimer(name string, fpsLimit int) *FPSTimer {
 		name:      name,
 		startTime: time.Now(),
 		rateLimit: frameDuration,
+		verbose:   verbose,
 	}
 }
 
@@ -42,7 +45,9 @@ func (t *FPSTimer) FrameEnd() {
 	t.frameCount++
 
 	if time.Since(t.startTime) >= time.Second {
-		fmt.Printf("%s: %d\n", t.name, t.frameCount)
+		if t.verbose {
+			fmt.Printf("%s: %d\n", t.name, t.frameCount)
+		}
 
 		t.startTime = time.Now()
 		t.frameCount = 0
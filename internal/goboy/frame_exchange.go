@@ -0,0 +1,63 @@
+package goboy
+
+import "sync"
+
+// A finished frame, as handed from the emulator goroutine to whatever is
+// displaying it
+type Frame struct {
+	// The PPU's frame counter when this frame finished
+	Number uint32
+	Pixels [FRAME_BUFFER_SIZE]uint32
+	// Only filled in when debug windows are enabled
+	VideoRam [0x2000]byte
+}
+
+// Triple buffered so that neither side ever waits on the other for more than a
+// pointer swap. The emulator fills the back buffer and swaps it with the middle
+// one at VBlank, and readers swap the middle one with the front buffer when
+// there's a newer frame.
+// @see https://en.wikipedia.org/wiki/Multiple_buffering#Triple_buffering
+type FrameExchange struct {
+	mutex  sync.Mutex
+	back   *Frame
+	middle *Frame
+	front  *Frame
+	// Whether the middle buffer holds a frame readers haven't seen yet
+	fresh bool
+}
+
+func NewFrameExchange() *FrameExchange {
+	return &FrameExchange{
+		back:   &Frame{},
+		middle: &Frame{},
+		front:  &Frame{},
+	}
+}
+
+// Called on the emulator goroutine each time a frame finishes
+func (exchange *FrameExchange) publish(ppu *PPU, includeVideoRam bool) {
+	exchange.back.Number = ppu.currentFrame
+	exchange.back.Pixels = *ppu.videoBuffer
+	if includeVideoRam {
+		copy(exchange.back.VideoRam[:], ppu.vram.data)
+	}
+
+	exchange.mutex.Lock()
+	exchange.back, exchange.middle = exchange.middle, exchange.back
+	exchange.fresh = true
+	exchange.mutex.Unlock()
+}
+
+// Returns the most recently finished frame. The frame stays valid until the
+// next call, and is safe to use from any goroutine.
+func (exchange *FrameExchange) Latest() *Frame {
+	exchange.mutex.Lock()
+	defer exchange.mutex.Unlock()
+
+	if exchange.fresh {
+		exchange.front, exchange.middle = exchange.middle, exchange.front
+		exchange.fresh = false
+	}
+
+	return exchange.front
+}
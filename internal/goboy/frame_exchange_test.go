@@ -0,0 +1,85 @@
+package goboy
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFrameExchange_OnlySwapsFreshFrames(t *testing.T) {
+	gameboy := newTestGameBoy(t)
+	exchange := NewFrameExchange()
+
+	gameboy.ppu.currentFrame = 1
+	gameboy.ppu.videoBuffer[0] = 0xFF112233
+	exchange.publish(gameboy.ppu, false)
+
+	first := exchange.Latest()
+	if first.Number != 1 || first.Pixels[0] != 0xFF112233 {
+		t.Fatalf("expected frame 1 to be published, got frame %d", first.Number)
+	}
+
+	if again := exchange.Latest(); again != first {
+		t.Errorf("expected the same frame until a new one is published")
+	}
+
+	gameboy.ppu.currentFrame = 2
+	exchange.publish(gameboy.ppu, false)
+	gameboy.ppu.currentFrame = 3
+	exchange.publish(gameboy.ppu, false)
+
+	if latest := exchange.Latest(); latest.Number != 3 {
+		t.Errorf("expected readers to skip to the newest frame, got frame %d", latest.Number)
+	}
+}
+
+// Mostly useful with -race, this drives the emulator the same way the UI does
+func TestRun_FramesAndInputFromAnotherGoroutine(t *testing.T) {
+	gameboy := newTestGameBoy(t)
+
+	stopped := make(chan struct{})
+	go func() {
+		gameboy.Run()
+		close(stopped)
+	}()
+
+	deadline := time.Now().Add(10 * time.Second)
+	for gameboy.LatestFrame().Number < 5 {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for frames")
+		}
+
+		gameboy.Press(JOYPAD_START)
+		gameboy.Release(JOYPAD_START)
+		time.Sleep(time.Millisecond)
+	}
+
+	gameboy.Stop()
+	<-stopped
+}
+
+func TestInputQueue_Apply(t *testing.T) {
+	gameboy := newTestGameBoy(t)
+
+	gameboy.Press(JOYPAD_A)
+	gameboy.Release(JOYPAD_A)
+	gameboy.Press(JOYPAD_START)
+	gameboy.input.apply(gameboy.joypad)
+
+	if gameboy.input.pending.Load() || len(gameboy.input.events) != 0 {
+		t.Error("expected applying the queue to empty it")
+	}
+
+	if gameboy.joypad.Check(JOYPAD_A) || !gameboy.joypad.Check(JOYPAD_START) {
+		t.Error("expected the events to be applied in order")
+	}
+}
+
+// With nothing queued, applying input is on every instruction's path, so it
+// should cost next to nothing
+func BenchmarkInputQueue_ApplyEmpty(b *testing.B) {
+	gameboy := newTestGameBoy(b)
+
+	for range b.N {
+		gameboy.input.apply(gameboy.joypad)
+	}
+}
@@ -1,76 +1,165 @@
 package goboy
 
 import (
+	"errors"
 	"fmt"
+	"os"
+	"sync/atomic"
 	"time"
+
+	"github.com/seashairo/goboy/internal/disasm"
 )
 
-const DEBUG = false
+type GameBoy struct {
+	options Options
 
-const ROM_PATH = "./data/roms/pokemon_red.gb"
+	joypad *Joypad
 
-// const ROM_PATH = "./data/roms/bgbtest.gb"
+	running atomic.Bool
+	paused  bool
+	cycles  uint64
 
-// const ROM_PATH = "./data/roms/dmg-acid2.gb"
+	cpu       *CPU
+	ppu       *PPU
+	cartridge *Cartridge
+	timer     *Timer
+	bus       MemoryBusser
+	io        *IO
+	apu       *APU
 
-// const ROM_PATH = "./data/roms/blargg/instr_timing.gb"
+	tpsTimer *FPSTimer
 
-// const ROM_PATH = "./data/roms/blargg/01-special.gb"
+	bootRom []byte
 
-// const ROM_PATH = "./data/roms/blargg/02-interrupts.gb"
+	// Work queued up by other goroutines (e.g. the UI) which needs to happen on
+	// the emulator goroutine between instructions
+	commands chan func()
+	input    InputQueue
 
-// const ROM_PATH = "./data/roms/blargg/03-op sp,hl.gb"
+	// Finished frames, for displaying on other goroutines
+	frames *FrameExchange
 
-// const ROM_PATH = "./data/roms/blargg/04-op r,imm.gb"
+	breakpointCallbacks []BreakpointCallback
+	lockupCallbacks     []LockupCallback
 
-// const ROM_PATH = "./data/roms/blargg/05-op rp.gb"
+	// Traces every instruction the CPU runs, if set
+	tracer *Tracer
+	// The file opened for options.TracePath, closed by Close
+	traceFile *os.File
+	// Labels for the ROM, if it came with any
+	symbols *disasm.Symbols
 
-// const ROM_PATH = "./data/roms/blargg/06-ld r,r.gb"
+	hooks MemoryHooks
+	// Game Genie and GameShark codes, whether they're on or not
+	cheats []Cheat
+}
 
-// const ROM_PATH = "./data/roms/blargg/07-jr,jp,call,ret,rst.gb"
+// Called whenever the CPU runs LD B, B
+type BreakpointCallback func()
 
-// const ROM_PATH = "./data/roms/blargg/08-misc instrs.gb"
+func NewGameBoy(options Options) (*GameBoy, error) {
+	cartridge, err := LoadCartridge(options.RomPath, options.SaveDir)
+	if err != nil {
+		return nil, err
+	}
 
-// const ROM_PATH = "./data/roms/blargg/09-op r,r.gb"
+	var bootRom []byte
+	if options.BootRomPath != "" {
+		bootRom, err = loadBootRom(options.BootRomPath)
+		if err != nil {
+			return nil, err
+		}
+	}
 
-// const ROM_PATH = "./data/roms/blargg/10-bit ops.gb"
+	symbols, err := LoadSymbolsFor(options.RomPath, options.SymbolPath)
+	if err != nil {
+		return nil, err
+	}
 
-// const ROM_PATH = "./data/roms/blargg/11-op a,(hl).gb"
+	cheats, err := LoadCheatsFor(options.RomPath, options.CheatPath)
+	if err != nil {
+		return nil, err
+	}
 
-type GameBoy struct {
-	joypad *Joypad
+	for _, code := range options.Cheats {
+		cheat, err := ParseCheat(code)
+		if err != nil {
+			return nil, err
+		}
+		cheats = append(cheats, cheat)
+	}
 
-	running bool
-	paused  bool
-	cycles  uint64
+	gameboy := newGameBoy(options, cartridge, bootRom)
+	gameboy.symbols = symbols
+	for _, cheat := range cheats {
+		gameboy.AddCheat(cheat)
+	}
 
-	cpu   *CPU
-	ppu   *PPU
-	timer *Timer
-	bus   MemoryBusser
-	io    *IO
-	apu   *APU
+	if options.TracePath != "" {
+		file, err := os.Create(options.TracePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open trace file: %w", err)
+		}
 
-	tpsTimer *FPSTimer
+		gameboy.traceFile = file
+		gameboy.SetTracer(NewTracer(file, options.Trace))
+	}
+
+	return gameboy, nil
+}
+
+// Builds a GameBoy around a ROM image that's already in memory rather than
+// loading one from options.RomPath. Battery backed RAM isn't persisted.
+func NewGameBoyFromRom(options Options, romData []byte, bootRom []byte) (*GameBoy, error) {
+	cartridge, err := NewCartridge(romData, "")
+	if err != nil {
+		return nil, err
+	}
+
+	if bootRom != nil {
+		if err := checkBootRom(bootRom); err != nil {
+			return nil, err
+		}
+	}
+
+	return newGameBoy(options, cartridge, bootRom), nil
 }
 
-func NewGameBoy() *GameBoy {
+func newGameBoy(options Options, cartridge *Cartridge, bootRom []byte) *GameBoy {
 	gameboy := &GameBoy{
-		running:  false,
-		paused:   false,
-		cycles:   0,
-		tpsTimer: NewFPSTimer("tps", 0),
+		options:   options,
+		cartridge: cartridge,
+		bootRom:   bootRom,
+		paused:    false,
+		tpsTimer:  NewFPSTimer("tps", 0, options.Verbose),
+		commands:  make(chan func(), 16),
+		frames:    NewFrameExchange(),
 	}
 
+	if options.Verbose {
+		cartridge.debugPrint()
+	}
+
+	gameboy.powerOn()
+
+	if options.SerialOutPath != "" {
+		gameboy.RegisterSerialCallback(func(value byte) {
+			appendSerialToFile(options.SerialOutPath, value)
+		})
+	}
+
+	return gameboy
+}
+
+func (gameboy *GameBoy) powerOn() {
 	bus := &Bus{}
 	lcd := NewLCD(gameboy, bus)
 
 	// Initialize all the Game Boy hardware
 	cpu := NewCPU(gameboy, bus)
-	ppu := NewPPU(gameboy, bus, lcd)
+	ppu := NewPPU(gameboy, bus, lcd, gameboy.options.fpsLimit(), gameboy.options.Verbose)
 	apu := NewAPU(gameboy)
 
-	cartridge := LoadCartridge(ROM_PATH)
 	wram := NewRAM(8192, WORK_RAM_START)
 	hram := NewRAM(127, HIGH_RAM_START)
 
@@ -81,8 +170,9 @@ func NewGameBoy() *GameBoy {
 	io := NewIO(gameboy, bus, timer, interruptFlagsRegister, lcd, joypad, apu)
 	interruptEnableRegister := NewInterruptRegister(0)
 	// And then put it on the bus so everything knows what it has access to
-	bus.Init(cartridge, ppu, wram, hram, io, interruptEnableRegister)
+	bus.Init(gameboy.cartridge, ppu, wram, hram, io, interruptEnableRegister, &gameboy.hooks)
 
+	gameboy.cycles = 0
 	gameboy.cpu = cpu
 	gameboy.timer = timer
 	gameboy.bus = bus
@@ -91,14 +181,54 @@ func NewGameBoy() *GameBoy {
 	gameboy.joypad = joypad
 	gameboy.apu = apu
 
-	return gameboy
+	if gameboy.bootRom != nil {
+		gameboy.mapBootRom()
+	}
+}
+
+// Resets the machine as if it had been switched off and on again. Cartridge RAM
+// and the clock survive, as they would on real hardware. This must not be
+// called while the GameBoy is running on another goroutine.
+func (gameboy *GameBoy) Reset() {
+	audioCallbacks := gameboy.apu.callbacks
+	serialCallbacks := gameboy.io.serial.callbacks
+
+	gameboy.cartridge.reset()
+	gameboy.powerOn()
+
+	gameboy.apu.callbacks = audioCallbacks
+	gameboy.io.serial.callbacks = serialCallbacks
+}
+
+// The boot ROM is mapped over the first 256 bytes of the cartridge, so anything
+// other than exactly 256 bytes can't be a DMG boot ROM
+func loadBootRom(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load boot ROM: %w", err)
+	}
+
+	if err := checkBootRom(data); err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+func checkBootRom(data []byte) error {
+	if len(data) != 0x100 {
+		return fmt.Errorf("failed to load boot ROM: got %d bytes, expected 256", len(data))
+	}
+
+	return nil
 }
 
 func (gameboy *GameBoy) Run() {
-	gameboy.running = true
-	gameboy.cpu.debugPrint()
+	gameboy.running.Store(true)
+
+	for gameboy.running.Load() {
+		gameboy.runCommands()
 
-	for gameboy.running {
 		if gameboy.paused {
 			time.Sleep(16 * time.Millisecond)
 			continue
@@ -107,9 +237,62 @@ func (gameboy *GameBoy) Run() {
 		gameboy.tpsTimer.FrameStart()
 		gameboy.cpu.Tick()
 		gameboy.tpsTimer.FrameEnd()
+
+		// There aren't any frames to pace the loop while the CPU is stopped, so
+		// just wait for a button press
+		if gameboy.cpu.stopped {
+			time.Sleep(time.Millisecond)
+		}
 	}
 
 	fmt.Println("GameBoy terminating")
+
+	if err := gameboy.Close(); err != nil {
+		fmt.Println(err)
+	}
+}
+
+// Saves battery backed RAM and finishes writing the trace, if there is one.
+// Run does this when it stops, and anything else driving the GameBoy should
+// call it once it's done.
+func (gameboy *GameBoy) Close() error {
+	err := gameboy.cartridge.SaveBattery()
+
+	// Without a lockup, the ring buffer's last instructions are written here
+	if gameboy.tracer != nil {
+		err = errors.Join(err, gameboy.tracer.Dump(), gameboy.tracer.Flush())
+	}
+
+	if gameboy.traceFile != nil {
+		err = errors.Join(err, gameboy.traceFile.Close())
+		gameboy.traceFile = nil
+	}
+
+	return err
+}
+
+func (gameboy *GameBoy) queueCommand(command func()) {
+	gameboy.commands <- command
+}
+
+func (gameboy *GameBoy) runCommands() {
+	gameboy.input.apply(gameboy.joypad)
+
+	for {
+		select {
+		case command := <-gameboy.commands:
+			command()
+		default:
+			return
+		}
+	}
+}
+
+// Called by the PPU each time it enters VBlank, i.e. once per frame
+func (gameboy *GameBoy) onVBlank() {
+	gameboy.frames.publish(gameboy.ppu, gameboy.options.DebugWindows)
+	gameboy.cartridge.tickBattery()
+	gameboy.applyGameShark()
 }
 
 func (gameboy *GameBoy) RequestInterrupt(kind InterruptKind) {
@@ -120,12 +303,65 @@ func (gameboy *GameBoy) ClearInterrupt(kind InterruptKind) {
 	gameboy.io.interrupts.SetInterrupt(kind, false)
 }
 
+// Presses and releases are safe from any goroutine, and take effect before the
+// next instruction
 func (gameboy *GameBoy) Press(button Button) {
-	gameboy.joypad.Press(button)
+	gameboy.input.push(button, true)
 }
 
 func (gameboy *GameBoy) Release(button Button) {
-	gameboy.joypad.Release(button)
+	gameboy.input.push(button, false)
+}
+
+// The number of frames the PPU has finished so far. Only safe to call on the
+// emulator goroutine.
+func (gameboy *GameBoy) FrameCount() uint32 {
+	return gameboy.ppu.currentFrame
+}
+
+// The PPU's LCD_WIDTH x LCD_HEIGHT buffer of ARGB pixels. This is the buffer
+// being drawn in to, not a copy, so it's only safe to use on the emulator
+// goroutine. Other goroutines should use LatestFrame.
+func (gameboy *GameBoy) Framebuffer() []uint32 {
+	return gameboy.ppu.videoBuffer[:]
+}
+
+// The most recently finished frame, safe to call from any goroutine. The frame
+// stays valid until the next call.
+func (gameboy *GameBoy) LatestFrame() *Frame {
+	return gameboy.frames.Latest()
+}
+
+// Reads a byte as the CPU would see it. This goes through the bus, so reading
+// registers with side effects has those side effects, but memory hooks aren't
+// called for it.
+func (gameboy *GameBoy) ReadMemory(address uint16) byte {
+	return gameboy.bus.peekByte(address)
+}
+
+func (gameboy *GameBoy) WriteMemory(address uint16, value byte) {
+	gameboy.bus.pokeByte(address, value)
+}
+
+func (gameboy *GameBoy) ReadRegister(register CpuRegister) uint16 {
+	return gameboy.cpu.registers.read(register)
+}
+
+// Runs a single instruction, or a single cycle if the CPU is halted, and
+// returns the number of M-cycles it took
+func (gameboy *GameBoy) Step() int {
+	start := gameboy.cycles
+
+	gameboy.runCommands()
+	gameboy.cpu.Tick()
+
+	return int(gameboy.cycles - start)
+}
+
+// True after the game runs STOP, until a button is pressed. No frames are drawn
+// in the meantime.
+func (gameboy *GameBoy) InStopMode() bool {
+	return gameboy.cpu.stopped
 }
 
 func (gameboy *GameBoy) Cycle(mCycles int) {
@@ -141,9 +377,72 @@ func (gameboy *GameBoy) Cycle(mCycles int) {
 }
 
 func (gameboy *GameBoy) Stop() {
-	gameboy.running = false
+	gameboy.running.Store(false)
 }
 
 func (gameboy *GameBoy) RegisterAudioCallback(callback AudioCallback) {
 	gameboy.apu.callbacks = append(gameboy.apu.callbacks, callback)
 }
+
+// Serial callbacks are called from the emulation goroutine with every byte sent
+// over the link cable. Nothing is ever sent back.
+func (gameboy *GameBoy) RegisterSerialCallback(callback SerialCallback) {
+	gameboy.io.serial.callbacks = append(gameboy.io.serial.callbacks, callback)
+}
+
+// Breakpoint callbacks are called from the emulation goroutine each time the
+// CPU runs LD B, B, just after it has run
+func (gameboy *GameBoy) RegisterBreakpointCallback(callback BreakpointCallback) {
+	gameboy.breakpointCallbacks = append(gameboy.breakpointCallbacks, callback)
+}
+
+// Lockup callbacks are called from the emulation goroutine when the CPU runs an
+// invalid opcode and locks up
+func (gameboy *GameBoy) RegisterLockupCallback(callback LockupCallback) {
+	gameboy.lockupCallbacks = append(gameboy.lockupCallbacks, callback)
+}
+
+// Memory hooks are called from the emulation goroutine for bus accesses in
+// their range. They stay registered when the GameBoy resets.
+func (gameboy *GameBoy) AddMemoryHook(hook MemoryHook) MemoryHookId {
+	return gameboy.hooks.Add(hook)
+}
+
+func (gameboy *GameBoy) RemoveMemoryHook(id MemoryHookId) {
+	gameboy.hooks.Remove(id)
+}
+
+// Starts tracing every instruction the CPU runs, or stops if tracer is nil
+func (gameboy *GameBoy) SetTracer(tracer *Tracer) {
+	if tracer != nil {
+		tracer.symbols = gameboy.symbols
+	}
+
+	gameboy.tracer = tracer
+}
+
+// Replaces the ROM's symbols, e.g. after rebuilding it
+func (gameboy *GameBoy) SetSymbols(symbols *disasm.Symbols) {
+	gameboy.symbols = symbols
+
+	if gameboy.tracer != nil {
+		gameboy.tracer.symbols = symbols
+	}
+}
+
+// True once the CPU has locked up, until the Game Boy is reset
+func (gameboy *GameBoy) LockedUp() bool {
+	return gameboy.cpu.lockedUp
+}
+
+func (gameboy *GameBoy) onSoftwareBreakpoint() {
+	for _, callback := range gameboy.breakpointCallbacks {
+		callback()
+	}
+}
+
+// Rumble callbacks are called from the emulation goroutine whenever an MBC5
+// rumble cartridge turns its motor on or off. Other cartridges never call them.
+func (gameboy *GameBoy) RegisterRumbleCallback(callback RumbleCallback) {
+	gameboy.cartridge.rumbleCallbacks = append(gameboy.cartridge.rumbleCallbacks, callback)
+}
@@ -0,0 +1,578 @@
+package goboy
+
+import (
+	"bufio"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// GDB has no idea what an SM83 is, so the target description tells it which
+// registers there are. They're sent as 16 bit little endian values in this
+// order.
+// @see https://sourceware.org/gdb/current/onlinedocs/gdb.html/Target-Descriptions.html
+const GDB_TARGET_XML = `<?xml version="1.0"?>
+<!DOCTYPE target SYSTEM "gdb-target.dtd">
+<target version="1.0">
+  <feature name="org.seashairo.goboy.sm83">
+    <reg name="af" bitsize="16" type="uint16" regnum="0"/>
+    <reg name="bc" bitsize="16" type="uint16"/>
+    <reg name="de" bitsize="16" type="uint16"/>
+    <reg name="hl" bitsize="16" type="uint16"/>
+    <reg name="sp" bitsize="16" type="data_ptr"/>
+    <reg name="pc" bitsize="16" type="code_ptr"/>
+  </feature>
+</target>
+`
+
+var GDB_REGISTERS = []CpuRegister{R_AF, R_BC, R_DE, R_HL, R_SP, R_PC}
+
+// GDB's own signal numbers, which stop replies use to say why the target
+// stopped. They don't always match the host's.
+const (
+	GDB_SIGINT  byte = 2
+	GDB_SIGILL  byte = 4
+	GDB_SIGTRAP byte = 5
+	GDB_SIGSTOP byte = 17
+)
+
+// How many bytes of a packet GDB is allowed to send us at once
+const GDB_PACKET_SIZE = 0x4000
+
+// Serves the GDB remote serial protocol, so GDB (or anything else that speaks
+// it) can debug whatever's running on a GameBoy. One connection is served at a
+// time, and the emulator only runs while the client has asked it to continue
+// or step, on the goroutine calling Serve.
+//
+// Breakpoints are on 16 bit addresses, unless the address is above 0xFFFF, in
+// which case the upper bits are the ROM bank, e.g. 0x14000 is 01:4000.
+// @see https://sourceware.org/gdb/current/onlinedocs/gdb.html/Remote-Protocol.html
+type GdbServer struct {
+	gameboy  *GameBoy
+	listener net.Listener
+
+	breakpoints     []Breakpoint
+	watchpoints     []Watchpoint
+	watchpointHooks []MemoryHookId
+
+	// The stop reply for a watchpoint hit part way through an instruction
+	watchpointHit string
+	// Set when the client sends a break (Ctrl-C) while the GameBoy is running
+	interrupted atomic.Bool
+	// Once the client has asked for no acks, it doesn't send or expect them
+	noAck atomic.Bool
+}
+
+// Listens on the given TCP address, e.g. localhost:2345
+func NewGdbServer(gameboy *GameBoy, address string) (*GdbServer, error) {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start GDB server: %w", err)
+	}
+
+	server := &GdbServer{
+		gameboy:  gameboy,
+		listener: listener,
+	}
+
+	return server, nil
+}
+
+func (server *GdbServer) Addr() net.Addr {
+	return server.listener.Addr()
+}
+
+// Accepts and serves connections one after another until Close is called
+func (server *GdbServer) Serve() error {
+	for {
+		conn, err := server.listener.Accept()
+		if errors.Is(err, net.ErrClosed) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := server.serveConnection(conn); err != nil {
+			fmt.Printf("GDB connection closed: %v\n", err)
+		}
+	}
+}
+
+// Stops accepting connections. Serve returns once the current one ends.
+func (server *GdbServer) Close() error {
+	return server.listener.Close()
+}
+
+func (server *GdbServer) serveConnection(conn net.Conn) error {
+	server.noAck.Store(false)
+	server.clearBreakpoints()
+
+	// Packets are read on another goroutine, so a break can come in while the
+	// GameBoy is running on this one
+	packets := make(chan string)
+	errs := make(chan error, 1)
+	go func() {
+		errs <- server.readPackets(bufio.NewReader(conn), conn, packets)
+		close(packets)
+	}()
+
+	// Closing the connection stops the reader, once it isn't stuck waiting for
+	// us to take a packet
+	defer func() {
+		conn.Close()
+		for range packets {
+		}
+	}()
+
+	for packet := range packets {
+		reply, done := server.handle(packet)
+
+		// Kill is the only packet which doesn't get a reply
+		if packet != "k" {
+			if err := writePacket(conn, reply); err != nil {
+				return err
+			}
+		}
+
+		if done {
+			return nil
+		}
+	}
+
+	// The client hanging up is how connections normally end
+	if err := <-errs; !errors.Is(err, io.EOF) {
+		return err
+	}
+
+	return nil
+}
+
+// Splits what the client sends into packets, acking them as it goes
+func (server *GdbServer) readPackets(reader *bufio.Reader, acks io.Writer, packets chan<- string) error {
+	for {
+		b, err := reader.ReadByte()
+		if err != nil {
+			return err
+		}
+
+		switch b {
+		case 0x03:
+			server.Interrupt()
+			continue
+		case '$':
+		default:
+			// Acks, and anything else between packets
+			continue
+		}
+
+		data, err := reader.ReadString('#')
+		if err != nil {
+			return err
+		}
+		data = strings.TrimSuffix(data, "#")
+
+		checksum := make([]byte, 2)
+		if _, err := io.ReadFull(reader, checksum); err != nil {
+			return err
+		}
+
+		if !server.noAck.Load() {
+			// Ask for anything that got mangled on the way to be sent again
+			if !strings.EqualFold(string(checksum), fmt.Sprintf("%2.2x", gdbChecksum(data))) {
+				acks.Write([]byte("-"))
+				continue
+			}
+
+			acks.Write([]byte("+"))
+		}
+
+		packets <- data
+	}
+}
+
+// Stops the GameBoy at the end of the current instruction if it's running, and
+// reports it to the client as SIGINT
+func (server *GdbServer) Interrupt() {
+	server.interrupted.Store(true)
+}
+
+func writePacket(conn net.Conn, data string) error {
+	_, err := fmt.Fprintf(conn, "$%s#%2.2x", data, gdbChecksum(data))
+	return err
+}
+
+func gdbChecksum(data string) byte {
+	sum := byte(0)
+	for i := 0; i < len(data); i++ {
+		sum += data[i]
+	}
+
+	return sum
+}
+
+// Returns the reply to a packet, and whether the connection should be closed
+// after sending it. Anything unsupported gets an empty reply.
+func (server *GdbServer) handle(packet string) (string, bool) {
+	if packet == "" {
+		return "", false
+	}
+
+	command, args := packet[0], packet[1:]
+
+	switch command {
+	case '?':
+		return gdbStopReply(GDB_SIGTRAP), false
+	case 'g':
+		return server.readRegisters(), false
+	case 'G':
+		return server.writeRegisters(args), false
+	case 'p':
+		return server.readRegister(args), false
+	case 'P':
+		return server.writeRegister(args), false
+	case 'm':
+		return server.readMemory(args), false
+	case 'M':
+		return server.writeMemory(args), false
+	case 'Z':
+		return server.addBreakpoint(args), false
+	case 'z':
+		return server.removeBreakpoint(args), false
+	case 's':
+		return server.resume(args, true), false
+	case 'c':
+		return server.resume(args, false), false
+	case 'H':
+		// There's only one thread
+		return "OK", false
+	case 'D':
+		server.clearBreakpoints()
+		return "OK", true
+	case 'k':
+		server.clearBreakpoints()
+		return "", true
+	case 'q', 'Q':
+		return server.handleQuery(packet), false
+	}
+
+	return "", false
+}
+
+func (server *GdbServer) handleQuery(packet string) string {
+	switch {
+	case strings.HasPrefix(packet, "qSupported"):
+		return fmt.Sprintf("PacketSize=%x;qXfer:features:read+;QStartNoAckMode+", GDB_PACKET_SIZE)
+	case packet == "QStartNoAckMode":
+		server.noAck.Store(true)
+		return "OK"
+	case packet == "qAttached":
+		return "1"
+	case packet == "qC":
+		return "QC1"
+	case packet == "qfThreadInfo":
+		return "m1"
+	case packet == "qsThreadInfo":
+		return "l"
+	case strings.HasPrefix(packet, "qXfer:features:read:target.xml:"):
+		return readXfer(GDB_TARGET_XML, strings.TrimPrefix(packet, "qXfer:features:read:target.xml:"))
+	}
+
+	return ""
+}
+
+// Sends part of a document starting at offset, "l" meaning it's the last part
+func readXfer(document string, args string) string {
+	offset, length, err := parseGdbPair(args, ",")
+	if err != nil {
+		return "E01"
+	}
+
+	if offset >= len(document) {
+		return "l"
+	}
+
+	end := min(offset+length, len(document))
+	if end == len(document) {
+		return "l" + document[offset:end]
+	}
+
+	return "m" + document[offset:end]
+}
+
+func gdbStopReply(signal byte) string {
+	return fmt.Sprintf("S%2.2x", signal)
+}
+
+// Registers are little endian hex
+func (server *GdbServer) readRegisters() string {
+	out := ""
+	for _, register := range GDB_REGISTERS {
+		out += gdbHex16(server.gameboy.cpu.registers.read(register))
+	}
+
+	return out
+}
+
+func (server *GdbServer) writeRegisters(args string) string {
+	data, err := hex.DecodeString(args)
+	if err != nil || len(data) != 2*len(GDB_REGISTERS) {
+		return "E01"
+	}
+
+	for i, register := range GDB_REGISTERS {
+		server.gameboy.cpu.registers.write(register, BytesToUint16(data[2*i+1], data[2*i]))
+	}
+
+	return "OK"
+}
+
+func (server *GdbServer) readRegister(args string) string {
+	index, err := strconv.ParseUint(args, 16, 8)
+	if err != nil || int(index) >= len(GDB_REGISTERS) {
+		return "E01"
+	}
+
+	return gdbHex16(server.gameboy.cpu.registers.read(GDB_REGISTERS[index]))
+}
+
+func (server *GdbServer) writeRegister(args string) string {
+	indexText, valueText, _ := strings.Cut(args, "=")
+	index, err := strconv.ParseUint(indexText, 16, 8)
+	if err != nil || int(index) >= len(GDB_REGISTERS) {
+		return "E01"
+	}
+
+	data, err := hex.DecodeString(valueText)
+	if err != nil || len(data) != 2 {
+		return "E01"
+	}
+
+	server.gameboy.cpu.registers.write(GDB_REGISTERS[index], BytesToUint16(data[1], data[0]))
+	return "OK"
+}
+
+func gdbHex16(value uint16) string {
+	hi, lo := Uint16ToBytes(value)
+	return fmt.Sprintf("%2.2x%2.2x", lo, hi)
+}
+
+// Memory goes through the bus, as the CPU would see it, but without taking any
+// time
+func (server *GdbServer) readMemory(args string) string {
+	address, length, err := parseGdbPair(args, ",")
+	if err != nil || length > GDB_PACKET_SIZE/2 || address+length > 0x10000 {
+		return "E01"
+	}
+
+	data := make([]byte, length)
+	for i := range data {
+		data[i] = server.gameboy.bus.peekByte(uint16(address + i))
+	}
+
+	return hex.EncodeToString(data)
+}
+
+func (server *GdbServer) writeMemory(args string) string {
+	location, values, _ := strings.Cut(args, ":")
+	address, length, err := parseGdbPair(location, ",")
+	if err != nil || address+length > 0x10000 {
+		return "E01"
+	}
+
+	data, err := hex.DecodeString(values)
+	if err != nil || len(data) != length {
+		return "E01"
+	}
+
+	for i, value := range data {
+		server.gameboy.bus.pokeByte(uint16(address+i), value)
+	}
+
+	return "OK"
+}
+
+// Z and z packets look like "type,address,kind". Kind is the length in bytes
+// for watchpoints, which can't run past the end of memory, and can be ignored
+// for breakpoints.
+func (server *GdbServer) addBreakpoint(args string) string {
+	kind, address, length, err := parseGdbBreakpoint(args)
+	if err != nil {
+		return "E01"
+	}
+
+	switch kind {
+	case 0, 1:
+		// Software and hardware breakpoints are the same thing here
+		server.breakpoints = append(server.breakpoints, gdbBreakpoint(address))
+	case 2, 3, 4:
+		if length < 1 || address+length > 0x10000 {
+			return "E01"
+		}
+
+		server.watchpoints = append(server.watchpoints, gdbWatchpoint(kind, address, length))
+		server.hookWatchpoints()
+	default:
+		return ""
+	}
+
+	return "OK"
+}
+
+func (server *GdbServer) removeBreakpoint(args string) string {
+	kind, address, length, err := parseGdbBreakpoint(args)
+	if err != nil {
+		return "E01"
+	}
+
+	switch kind {
+	case 0, 1:
+		if i := slices.Index(server.breakpoints, gdbBreakpoint(address)); i >= 0 {
+			server.breakpoints = slices.Delete(server.breakpoints, i, i+1)
+		}
+	case 2, 3, 4:
+		if i := slices.Index(server.watchpoints, gdbWatchpoint(kind, address, length)); i >= 0 {
+			server.watchpoints = slices.Delete(server.watchpoints, i, i+1)
+		}
+		server.hookWatchpoints()
+	default:
+		return ""
+	}
+
+	return "OK"
+}
+
+func gdbBreakpoint(address int) Breakpoint {
+	if address > 0xFFFF {
+		return Breakpoint{Bank: address >> 16, Address: uint16(address)}
+	}
+
+	return Breakpoint{Bank: -1, Address: uint16(address)}
+}
+
+// Kind 2 is a write watchpoint, 3 read, and 4 either
+func gdbWatchpoint(kind int, address int, length int) Watchpoint {
+	return Watchpoint{
+		Address: uint16(address),
+		Length:  length,
+		Read:    kind == 3 || kind == 4,
+		Write:   kind == 2 || kind == 4,
+	}
+}
+
+// Breakpoints and watchpoints belong to the client which set them, so they're
+// cleared when it detaches and before the next one connects
+func (server *GdbServer) clearBreakpoints() {
+	server.breakpoints = nil
+	server.watchpoints = nil
+	server.watchpointHit = ""
+	server.hookWatchpoints()
+}
+
+func (server *GdbServer) hookWatchpoints() {
+	server.watchpointHooks = hookWatchpoints(
+		server.gameboy, server.watchpointHooks, server.watchpoints, server.checkWatchpoints,
+	)
+}
+
+func (server *GdbServer) checkWatchpoints(access MemoryAccess) {
+	if server.watchpointHit != "" {
+		return
+	}
+
+	i := findWatchpoint(server.watchpoints, access)
+	if i < 0 {
+		return
+	}
+
+	reason := "rwatch"
+	if watchpoint := server.watchpoints[i]; watchpoint.Read && watchpoint.Write {
+		reason = "awatch"
+	} else if watchpoint.Write {
+		reason = "watch"
+	}
+
+	server.watchpointHit = fmt.Sprintf("T%2.2x%s:%x;", GDB_SIGTRAP, reason, access.Address)
+}
+
+// Runs one instruction, or until something stops the GameBoy, and returns the
+// stop reply. The client can say where to carry on from.
+func (server *GdbServer) resume(args string, step bool) string {
+	gameboy := server.gameboy
+	registers := gameboy.cpu.registers
+
+	if args != "" {
+		address, err := strconv.ParseUint(args, 16, 16)
+		if err != nil {
+			return "E01"
+		}
+		registers.write(R_PC, uint16(address))
+	}
+
+	server.interrupted.Store(false)
+	server.watchpointHit = ""
+
+	for {
+		if gameboy.LockedUp() {
+			return gdbStopReply(GDB_SIGILL)
+		}
+
+		gameboy.Step()
+
+		switch {
+		case server.watchpointHit != "":
+			return server.watchpointHit
+		case gameboy.LockedUp():
+			return gdbStopReply(GDB_SIGILL)
+		case gameboy.InStopMode():
+			return gdbStopReply(GDB_SIGSTOP)
+		case step:
+			return gdbStopReply(GDB_SIGTRAP)
+		case findBreakpoint(server.breakpoints, gameboy.cartridge, registers.read(R_PC)) >= 0:
+			return gdbStopReply(GDB_SIGTRAP)
+		case server.interrupted.Load():
+			return gdbStopReply(GDB_SIGINT)
+		}
+	}
+}
+
+// Parses two hex numbers with a separator between, like "c000,10"
+func parseGdbPair(args string, separator string) (int, int, error) {
+	firstText, secondText, found := strings.Cut(args, separator)
+	if !found {
+		return 0, 0, fmt.Errorf("expected two values in %q", args)
+	}
+
+	first, err := strconv.ParseUint(firstText, 16, 32)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	second, err := strconv.ParseUint(secondText, 16, 32)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return int(first), int(second), nil
+}
+
+func parseGdbBreakpoint(args string) (int, int, int, error) {
+	fields := strings.Split(args, ",")
+	if len(fields) < 3 {
+		return 0, 0, 0, fmt.Errorf("expected type, address and kind in %q", args)
+	}
+
+	kind, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return 0, 0, 0, err
+	}
+
+	address, length, err := parseGdbPair(fields[1]+","+fields[2], ",")
+	return kind, address, length, err
+}
@@ -0,0 +1,242 @@
+package goboy
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+type gdbTestClient struct {
+	t      *testing.T
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+func newGdbTestClient(t *testing.T, program []byte) (*GameBoy, *gdbTestClient) {
+	t.Helper()
+
+	gameboy := newWramTestGameBoy(t, program, false)
+	server, err := NewGdbServer(gameboy, "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	served := make(chan error)
+	go func() { served <- server.Serve() }()
+
+	conn, err := net.Dial("tcp", server.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.SetDeadline(time.Now().Add(10 * time.Second))
+
+	t.Cleanup(func() {
+		conn.Close()
+		server.Close()
+		if err := <-served; err != nil {
+			t.Errorf("expected Serve to stop cleanly, got %v", err)
+		}
+	})
+
+	return gameboy, &gdbTestClient{t: t, conn: conn, reader: bufio.NewReader(conn)}
+}
+
+// Sends a packet and returns the reply
+func (client *gdbTestClient) send(packet string) string {
+	client.t.Helper()
+
+	fmt.Fprintf(client.conn, "$%s#%2.2x", packet, gdbChecksum(packet))
+
+	if ack, err := client.reader.ReadByte(); err != nil || ack != '+' {
+		client.t.Fatalf("%s: expected an ack, got %q, %v", packet, ack, err)
+	}
+
+	return client.readPacket()
+}
+
+func (client *gdbTestClient) readPacket() string {
+	client.t.Helper()
+
+	if _, err := client.reader.ReadString('$'); err != nil {
+		client.t.Fatal(err)
+	}
+
+	data, err := client.reader.ReadString('#')
+	if err != nil {
+		client.t.Fatal(err)
+	}
+	data = strings.TrimSuffix(data, "#")
+
+	checksum := make([]byte, 2)
+	if _, err := io.ReadFull(client.reader, checksum); err != nil {
+		client.t.Fatal(err)
+	}
+	if string(checksum) != fmt.Sprintf("%2.2x", gdbChecksum(data)) {
+		client.t.Errorf("%q: bad checksum %s", data, checksum)
+	}
+
+	return data
+}
+
+func (client *gdbTestClient) expect(packet string, expected string) {
+	client.t.Helper()
+
+	if reply := client.send(packet); reply != expected {
+		client.t.Errorf("%s: expected %q, got %q", packet, expected, reply)
+	}
+}
+
+// INC A; LD (0xC100), A; INC B; JR -2, which loops on INC B forever
+var GDB_TEST_PROGRAM = []byte{0x3C, 0xEA, 0x00, 0xC1, 0x04, 0x18, 0xFD}
+
+func TestGdb_Registers(t *testing.T) {
+	_, client := newGdbTestClient(t, GDB_TEST_PROGRAM)
+
+	client.expect("qSupported:multiprocess+;swbreak+", "PacketSize=4000;qXfer:features:read+;QStartNoAckMode+")
+	client.expect("?", "S05")
+	// AF, BC, DE, HL, SP, PC, little endian
+	client.expect("g", "b0001300d8004d01f0df00c0")
+	client.expect("p5", "00c0")
+
+	client.expect("P3=3412", "OK")
+	client.expect("p3", "3412")
+
+	client.expect("G"+"f0aa"+"0100"+"0200"+"0300"+"fedf"+"04c0", "OK")
+	client.expect("g", "f0aa010002000300fedf04c0")
+}
+
+func TestGdb_TargetDescription(t *testing.T) {
+	_, client := newGdbTestClient(t, GDB_TEST_PROGRAM)
+
+	document := ""
+	for {
+		reply := client.send(fmt.Sprintf("qXfer:features:read:target.xml:%x,%x", len(document), 100))
+		document += reply[1:]
+		if reply[0] == 'l' {
+			break
+		}
+	}
+
+	if document != GDB_TARGET_XML {
+		t.Errorf("expected the target description back in pieces, got %q", document)
+	}
+}
+
+func TestGdb_Memory(t *testing.T) {
+	gameboy, client := newGdbTestClient(t, GDB_TEST_PROGRAM)
+
+	client.expect("mc000,4", "3cea00c1")
+	client.expect("Mc200,3:010203", "OK")
+	client.expect("mc200,3", "010203")
+
+	// Reads and writes can't wrap around past the end of memory
+	client.expect("mfffe,2", "0000")
+	client.expect("mffff,2", "E01")
+	client.expect("Mffff,2:0102", "E01")
+	client.expect("m10000,1", "E01")
+
+	if value := gameboy.ReadMemory(INTERRUPT_ENABLE_REGISTER_START); value != 0x00 {
+		t.Errorf("expected a rejected write not to write anything, got IE 0x%2.2X", value)
+	}
+
+	if value := gameboy.ReadMemory(0xC201); value != 0x02 {
+		t.Errorf("expected the write to go through the bus, got 0x%2.2X", value)
+	}
+}
+
+func TestGdb_StepAndContinue(t *testing.T) {
+	gameboy, client := newGdbTestClient(t, GDB_TEST_PROGRAM)
+
+	client.expect("s", "S05")
+	client.expect("p5", "01c0")
+
+	client.expect("Z0,c004,1", "OK")
+	client.expect("c", "S05")
+	client.expect("p5", "04c0")
+
+	// Around the loop and back to the breakpoint again
+	client.expect("c", "S05")
+	if b := gameboy.ReadRegister(R_B); b != 1 {
+		t.Errorf("expected one trip round the loop, B is %d", b)
+	}
+
+	client.expect("z0,c004,1", "OK")
+	client.expect("Z0,1c004,1", "OK")
+
+	// The loop never ends, so this has to be interrupted
+	fmt.Fprintf(client.conn, "$c#%2.2x", gdbChecksum("c"))
+	if ack, _ := client.reader.ReadByte(); ack != '+' {
+		t.Fatalf("expected an ack, got %q", ack)
+	}
+	time.Sleep(10 * time.Millisecond)
+	client.conn.Write([]byte{0x03})
+
+	if reply := client.readPacket(); reply != "S02" {
+		t.Errorf("expected to stop for the interrupt, got %q", reply)
+	}
+}
+
+func TestGdb_Watchpoints(t *testing.T) {
+	_, client := newGdbTestClient(t, GDB_TEST_PROGRAM)
+
+	client.expect("Z2,c100,1", "OK")
+	client.expect("c", "T05watch:c100;")
+	client.expect("p5", "04c0")
+	client.expect("z2,c100,1", "OK")
+
+	// Reads of the opcode count as reads too
+	client.expect("Z3,c004,1", "OK")
+	client.expect("c", "T05rwatch:c004;")
+}
+
+func TestGdb_WatchpointRanges(t *testing.T) {
+	gameboy, client := newGdbTestClient(t, GDB_TEST_PROGRAM)
+
+	// Covers C0FE to C101, so the write to C100 is in the middle
+	client.expect("Z2,c0fe,4", "OK")
+	if len(gameboy.hooks.hooks) != 1 {
+		t.Errorf("expected one hook for the whole range, got %d", len(gameboy.hooks.hooks))
+	}
+
+	client.expect("c", "T05watch:c100;")
+	client.expect("z2,c0fe,4", "OK")
+
+	client.expect("Z2,fff0,10", "OK")
+	client.expect("Z2,fff0,11", "E01")
+	client.expect("Z2,0,ffffffff", "E01")
+	client.expect("Z2,c000,0", "E01")
+}
+
+func TestGdb_DetachClearsBreakpoints(t *testing.T) {
+	for _, packet := range []string{"D", "k"} {
+		t.Run(packet, func(t *testing.T) {
+			gameboy, client := newGdbTestClient(t, GDB_TEST_PROGRAM)
+
+			client.expect("Z0,c004,1", "OK")
+			client.expect("Z2,c100,1", "OK")
+
+			fmt.Fprintf(client.conn, "$%s#%2.2x", packet, gdbChecksum(packet))
+
+			// The server hangs up once it's done
+			if _, err := io.ReadAll(client.reader); err != nil {
+				t.Fatal(err)
+			}
+
+			if len(gameboy.hooks.hooks) != 0 {
+				t.Errorf("expected the watchpoint's hook to be removed, got %d hooks", len(gameboy.hooks.hooks))
+			}
+		})
+	}
+}
+
+func TestGdb_Lockup(t *testing.T) {
+	_, client := newGdbTestClient(t, []byte{0x00, 0xD3})
+
+	client.expect("c", "S04")
+	client.expect("s", "S04")
+	client.expect("D", "OK")
+}
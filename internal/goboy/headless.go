@@ -0,0 +1,60 @@
+package goboy
+
+import "slices"
+
+// Drives a GameBoy on the calling goroutine with no window, audio device or
+// frame limiting, so frames run as fast as the host allows. Useful for tests
+// and tools which want to inspect the output rather than watch it.
+type HeadlessRunner struct {
+	gameboy *GameBoy
+	samples []int16
+}
+
+func NewHeadlessRunner(options Options) (*HeadlessRunner, error) {
+	options.Headless = true
+	options.Speed = 0
+
+	gameboy, err := NewGameBoy(options)
+	if err != nil {
+		return nil, err
+	}
+
+	runner := &HeadlessRunner{gameboy: gameboy}
+
+	gameboy.RegisterAudioCallback(func(left int16, right int16) {
+		runner.samples = append(runner.samples, left, right)
+	})
+
+	return runner, nil
+}
+
+func (runner *HeadlessRunner) GameBoy() *GameBoy {
+	return runner.gameboy
+}
+
+// Runs until the PPU has finished another n frames, or until the game runs STOP
+// and waits for a button press. Returns a copy of the last frame and the
+// interleaved left/right samples generated along the way.
+func (runner *HeadlessRunner) RunFrames(n int) ([]uint32, []int16) {
+	gameboy := runner.gameboy
+	target := gameboy.ppu.currentFrame + uint32(n)
+
+	for gameboy.ppu.currentFrame != target {
+		gameboy.Step()
+
+		// Give the caller a chance to press a button
+		if gameboy.InStopMode() {
+			break
+		}
+	}
+
+	samples := runner.samples
+	runner.samples = nil
+
+	return slices.Clone(gameboy.ppu.videoBuffer[:]), samples
+}
+
+// Flushes battery backed RAM and the trace, as the GameBoy would when stopping
+func (runner *HeadlessRunner) Close() error {
+	return runner.gameboy.Close()
+}
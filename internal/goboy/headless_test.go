@@ -0,0 +1,39 @@
+package goboy
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestHeadlessRunner_RunFrames(t *testing.T) {
+	options := DefaultOptions()
+	options.RomPath = TEST_ROM_PATH
+
+	runner, err := NewHeadlessRunner(options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer runner.Close()
+
+	frame, samples := runner.RunFrames(10)
+
+	if got := runner.GameBoy().FrameCount(); got != 10 {
+		t.Errorf("expected 10 frames to have run, got %d", got)
+	}
+
+	if len(frame) != LCD_WIDTH*LCD_HEIGHT {
+		t.Errorf("expected a %dx%d frame, got %d pixels", LCD_WIDTH, LCD_HEIGHT, len(frame))
+	}
+
+	if len(samples) == 0 || len(samples)%2 != 0 {
+		t.Errorf("expected pairs of audio samples, got %d samples", len(samples))
+	}
+
+	// Later frames keep drawing in to the PPU's buffer, the returned frame
+	// shouldn't change along with it
+	copied := slices.Clone(frame)
+	runner.RunFrames(1)
+	if !slices.Equal(frame, copied) {
+		t.Errorf("expected the returned frame to be a copy")
+	}
+}
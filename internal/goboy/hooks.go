@@ -0,0 +1,167 @@
+package goboy
+
+import "fmt"
+
+type MemoryHookKind byte
+
+const (
+	// Called after something reads from the bus
+	HOOK_READ MemoryHookKind = 1 << iota
+	// Called after something writes to the bus
+	HOOK_WRITE
+	// Called just before the CPU runs the instruction at an address
+	HOOK_EXECUTE
+)
+
+func (kind MemoryHookKind) String() string {
+	switch kind {
+	case HOOK_READ:
+		return "read"
+	case HOOK_WRITE:
+		return "write"
+	case HOOK_EXECUTE:
+		return "execute"
+	}
+
+	return fmt.Sprintf("MemoryHookKind(%d)", byte(kind))
+}
+
+// What a hook was called for
+type MemoryAccess struct {
+	Kind MemoryHookKind
+	// Accesses to echo RAM have the address in work RAM they mirror
+	Address uint16
+	// The byte read or written, or the opcode about to run
+	Value byte
+	// The ROM bank mapped at Address, or -1 if it isn't in ROM
+	RomBank int
+	// The cartridge RAM bank mapped at Address, or -1 if it isn't in cartridge
+	// RAM or the MBC3 clock is mapped there instead
+	RamBank int
+}
+
+func (access MemoryAccess) String() string {
+	return fmt.Sprintf("%s 0x%02X at 0x%04X", access.Kind, access.Value, access.Address)
+}
+
+type MemoryHook struct {
+	// Which accesses to call Callback for. Kinds can be combined, e.g.
+	// HOOK_READ | HOOK_WRITE.
+	Kind MemoryHookKind
+	// The addresses to watch, inclusive. Echo RAM is watched through the work
+	// RAM it mirrors, so a hook on 0xC000 sees accesses to 0xE000 too.
+	Start uint16
+	End   uint16
+	// Only accesses to ROM or cartridge RAM while this bank is mapped call
+	// Callback, or -1 for any bank. Other areas of memory aren't banked, so it
+	// doesn't matter there.
+	Bank int
+	// Called from the emulation goroutine. It's fine to add or remove hooks
+	// from here, but reading memory should be done with peekByte (or
+	// GameBoy.ReadMemory) so it doesn't call hooks itself.
+	Callback func(access MemoryAccess)
+}
+
+type MemoryHookId int
+
+type registeredHook struct {
+	id MemoryHookId
+	MemoryHook
+}
+
+// Hooks on bus accesses, for anything that wants to watch memory, like
+// debuggers, cheats or achievements. They belong to the GameBoy rather than the
+// bus, so they stay registered when it resets. Like the other callbacks, add
+// and remove them before running the GameBoy or from the emulation goroutine.
+type MemoryHooks struct {
+	hooks  []registeredHook
+	nextId MemoryHookId
+
+	// The kinds of hook covering each 256 byte page of memory. Accesses nothing
+	// is watching only cost a lookup in here, so having no hooks is as good as
+	// free.
+	pages [256]MemoryHookKind
+}
+
+func (hooks *MemoryHooks) Add(hook MemoryHook) MemoryHookId {
+	hooks.nextId++
+
+	hooks.hooks = append(hooks.hooks, registeredHook{id: hooks.nextId, MemoryHook: hook})
+	hooks.updatePages()
+
+	return hooks.nextId
+}
+
+// Removes a hook added by Add. Does nothing if it's already gone.
+func (hooks *MemoryHooks) Remove(id MemoryHookId) {
+	// Hooks can be removed from a callback, while fire is looping over the old
+	// slice, so this makes a new one rather than shuffling that one around
+	remaining := make([]registeredHook, 0, len(hooks.hooks))
+	for _, hook := range hooks.hooks {
+		if hook.id != id {
+			remaining = append(remaining, hook)
+		}
+	}
+
+	hooks.hooks = remaining
+	hooks.updatePages()
+}
+
+func (hooks *MemoryHooks) updatePages() {
+	hooks.pages = [256]MemoryHookKind{}
+
+	for _, hook := range hooks.hooks {
+		for page := int(hook.Start >> 8); page <= int(hook.End>>8); page++ {
+			hooks.pages[page] |= hook.Kind
+		}
+	}
+
+	// Echo RAM pages are watched whenever the work RAM they mirror is, so
+	// watching doesn't have to translate addresses
+	for page := ECHO_RAM_START >> 8; page <= ECHO_RAM_END>>8; page++ {
+		hooks.pages[page] = hooks.pages[page-0x20]
+	}
+}
+
+// Whether any hook of the given kind might want an access at an address. This
+// is the only part of hooks on the bus's hot path.
+func (hooks *MemoryHooks) watching(kind MemoryHookKind, address uint16) bool {
+	return hooks.pages[address>>8]&kind != 0
+}
+
+func (hooks *MemoryHooks) fire(kind MemoryHookKind, address uint16, value byte, cartridge *Cartridge) {
+	if Between(address, ECHO_RAM_START, ECHO_RAM_END) {
+		address -= ECHO_RAM_START - WORK_RAM_START
+	}
+
+	access := MemoryAccess{
+		Kind:    kind,
+		Address: address,
+		Value:   value,
+		RomBank: cartridge.romBankAt(address),
+		RamBank: cartridge.ramBankAt(address),
+	}
+
+	for _, hook := range hooks.hooks {
+		if hook.Kind&kind == 0 || address < hook.Start || address > hook.End {
+			continue
+		}
+
+		if hook.Bank >= 0 && !access.inBank(hook.Bank) {
+			continue
+		}
+
+		hook.Callback(access)
+	}
+}
+
+func (access MemoryAccess) inBank(bank int) bool {
+	switch {
+	case access.Address <= SWITCHABLE_ROM_BANK_END:
+		return access.RomBank == bank
+	case Between(access.Address, EXTERNAL_RAM_START, EXTERNAL_RAM_END):
+		return access.RamBank == bank
+	}
+
+	return true
+}
@@ -0,0 +1,226 @@
+package goboy
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+)
+
+func recordHook(gameboy *GameBoy, hook MemoryHook) (*[]MemoryAccess, MemoryHookId) {
+	accesses := &[]MemoryAccess{}
+	hook.Callback = func(access MemoryAccess) {
+		*accesses = append(*accesses, access)
+	}
+
+	return accesses, gameboy.AddMemoryHook(hook)
+}
+
+func TestMemoryHooks_ReadWrite(t *testing.T) {
+	// LD (C100), A; LD A, (C101); LD (E102), A
+	gameboy := newWramTestGameBoy(t, []byte{0xEA, 0x00, 0xC1, 0xFA, 0x01, 0xC1, 0xEA, 0x02, 0xE1}, false)
+	gameboy.cpu.registers.a = 0x42
+	gameboy.bus.pokeByte(0xC101, 0x99)
+
+	writes, _ := recordHook(gameboy, MemoryHook{Kind: HOOK_WRITE, Start: 0xC100, End: 0xC1FF, Bank: -1})
+	reads, _ := recordHook(gameboy, MemoryHook{Kind: HOOK_READ, Start: 0xC101, End: 0xC101, Bank: -1})
+	echoes, _ := recordHook(gameboy, MemoryHook{Kind: HOOK_READ | HOOK_WRITE, Start: 0xE100, End: 0xE1FF, Bank: -1})
+	mirrored, _ := recordHook(gameboy, MemoryHook{Kind: HOOK_WRITE, Start: 0xC102, End: 0xC102, Bank: -1})
+
+	for range 3 {
+		gameboy.Step()
+	}
+
+	expectedWrites := []MemoryAccess{
+		{Kind: HOOK_WRITE, Address: 0xC100, Value: 0x42, RomBank: -1, RamBank: -1},
+		{Kind: HOOK_WRITE, Address: 0xC102, Value: 0x99, RomBank: -1, RamBank: -1},
+	}
+	if !slices.Equal(*writes, expectedWrites) {
+		t.Errorf("expected writes %v, got %v", expectedWrites, *writes)
+	}
+
+	expectedReads := []MemoryAccess{{Kind: HOOK_READ, Address: 0xC101, Value: 0x99, RomBank: -1, RamBank: -1}}
+	if !slices.Equal(*reads, expectedReads) {
+		t.Errorf("expected reads %v, got %v", expectedReads, *reads)
+	}
+
+	// Echo RAM is hooked by the address it mirrors, not the one the CPU used
+	expectedMirrored := []MemoryAccess{{Kind: HOOK_WRITE, Address: 0xC102, Value: 0x99, RomBank: -1, RamBank: -1}}
+	if !slices.Equal(*mirrored, expectedMirrored) {
+		t.Errorf("expected echo RAM accesses %v, got %v", expectedMirrored, *mirrored)
+	}
+
+	if len(*echoes) != 0 {
+		t.Errorf("expected hooks on echo RAM addresses not to be called, got %v", *echoes)
+	}
+}
+
+func TestMemoryHooks_Execute(t *testing.T) {
+	// NOP; INC A; NOP
+	gameboy := newWramTestGameBoy(t, []byte{0x00, 0x3C, 0x00}, false)
+
+	executes, _ := recordHook(gameboy, MemoryHook{
+		Kind:  HOOK_EXECUTE,
+		Start: WRAM_TEST_PROGRAM_START + 1,
+		End:   WRAM_TEST_PROGRAM_START + 1,
+		Bank:  -1,
+	})
+	reads, _ := recordHook(gameboy, MemoryHook{
+		Kind:  HOOK_READ,
+		Start: WRAM_TEST_PROGRAM_START,
+		End:   WRAM_TEST_PROGRAM_START + 2,
+		Bank:  -1,
+	})
+
+	for range 3 {
+		gameboy.Step()
+	}
+
+	expected := []MemoryAccess{{Kind: HOOK_EXECUTE, Address: WRAM_TEST_PROGRAM_START + 1, Value: 0x3C, RomBank: -1, RamBank: -1}}
+	if !slices.Equal(*executes, expected) {
+		t.Errorf("expected %v, got %v", expected, *executes)
+	}
+
+	// Fetching opcodes is a read like any other
+	if len(*reads) != 3 {
+		t.Errorf("expected 3 opcode fetches, got %v", *reads)
+	}
+}
+
+func TestMemoryHooks_Banks(t *testing.T) {
+	gameboy := newTestGameBoy(t)
+
+	anyBank, _ := recordHook(gameboy, MemoryHook{Kind: HOOK_READ, Start: 0x4000, End: 0x4000, Bank: -1})
+	bankTwo, _ := recordHook(gameboy, MemoryHook{Kind: HOOK_READ, Start: 0x4000, End: 0x4000, Bank: 2})
+	// Banks don't mean anything outside ROM and cartridge RAM
+	wram, _ := recordHook(gameboy, MemoryHook{Kind: HOOK_READ, Start: 0xC000, End: 0xC000, Bank: 2})
+
+	gameboy.bus.readByte(0x4000)
+	gameboy.bus.writeByte(0x2000, 0x02)
+	gameboy.bus.readByte(0x4000)
+	gameboy.bus.readByte(0xC000)
+
+	if banks := []int{(*anyBank)[0].RomBank, (*anyBank)[1].RomBank}; !slices.Equal(banks, []int{1, 2}) {
+		t.Errorf("expected reads from banks 1 and 2, got %v", banks)
+	}
+
+	if len(*bankTwo) != 1 || (*bankTwo)[0].RomBank != 2 {
+		t.Errorf("expected the bank 2 hook to only see bank 2, got %v", *bankTwo)
+	}
+
+	if len(*wram) != 1 {
+		t.Errorf("expected the WRAM hook to ignore its bank, got %v", *wram)
+	}
+}
+
+func TestMemoryHooks_Remove(t *testing.T) {
+	gameboy := newTestGameBoy(t)
+
+	first, firstId := recordHook(gameboy, MemoryHook{Kind: HOOK_WRITE, Start: 0xC000, End: 0xC0FF, Bank: -1})
+	second, secondId := recordHook(gameboy, MemoryHook{Kind: HOOK_WRITE, Start: 0xC000, End: 0xC000, Bank: -1})
+
+	gameboy.bus.writeByte(0xC000, 1)
+	gameboy.RemoveMemoryHook(firstId)
+	gameboy.bus.writeByte(0xC000, 2)
+	// Removing twice does nothing
+	gameboy.RemoveMemoryHook(firstId)
+
+	if len(*first) != 1 || len(*second) != 2 {
+		t.Errorf("expected 1 write before removing and 2 for the other hook, got %v and %v", *first, *second)
+	}
+
+	gameboy.RemoveMemoryHook(secondId)
+	if gameboy.hooks.watching(HOOK_WRITE, 0xC080) {
+		t.Error("expected the page to stop being watched once its hook was removed")
+	}
+}
+
+func TestMemoryHooks_Reset(t *testing.T) {
+	gameboy := newTestGameBoy(t)
+
+	writes, _ := recordHook(gameboy, MemoryHook{Kind: HOOK_WRITE, Start: 0xC000, End: 0xC000, Bank: -1})
+
+	gameboy.Reset()
+	gameboy.bus.writeByte(0xC000, 1)
+
+	if len(*writes) != 1 {
+		t.Errorf("expected the hook to survive a reset, got %v", *writes)
+	}
+}
+
+func TestMemoryHooks_Peek(t *testing.T) {
+	gameboy := newTestGameBoy(t)
+
+	accesses, _ := recordHook(gameboy, MemoryHook{Kind: HOOK_READ | HOOK_WRITE, Start: 0x0000, End: 0xFFFF, Bank: -1})
+
+	gameboy.WriteMemory(0xC000, 1)
+	gameboy.ReadMemory(0xC000)
+
+	if len(*accesses) != 0 {
+		t.Errorf("expected peeking and poking not to call hooks, got %v", *accesses)
+	}
+}
+
+// Compares bus reads with hooks against peekByte, which never checks them. With
+// no hooks, or none on the page being read, readByte should be about as fast.
+func BenchmarkBus_readByte(b *testing.B) {
+	benchmarks := []struct {
+		name  string
+		hooks []MemoryHook
+	}{
+		{"NoHooks", nil},
+		{"HookElsewhere", []MemoryHook{{Kind: HOOK_READ | HOOK_WRITE, Start: 0xA000, End: 0xBFFF, Bank: -1}}},
+		{"HookOnPage", []MemoryHook{{Kind: HOOK_READ, Start: 0xC0FF, End: 0xC0FF, Bank: -1}}},
+	}
+
+	for _, benchmark := range benchmarks {
+		b.Run(benchmark.name, func(b *testing.B) {
+			gameboy := newTestGameBoy(b)
+			for _, hook := range benchmark.hooks {
+				hook.Callback = func(access MemoryAccess) {}
+				gameboy.AddMemoryHook(hook)
+			}
+
+			b.ResetTimer()
+			for i := range b.N {
+				gameboy.bus.readByte(WORK_RAM_START + uint16(i&0x7F))
+			}
+		})
+	}
+
+	b.Run("Peek", func(b *testing.B) {
+		gameboy := newTestGameBoy(b)
+
+		b.ResetTimer()
+		for i := range b.N {
+			gameboy.bus.peekByte(WORK_RAM_START + uint16(i&0x7F))
+		}
+	})
+}
+
+// The same comparison over whole frames of a real ROM
+func BenchmarkMemoryHooks_Frame(b *testing.B) {
+	for _, hooks := range []int{0, 8} {
+		b.Run(fmt.Sprintf("%dHooks", hooks), func(b *testing.B) {
+			gameboy := newTestGameBoy(b)
+			for i := range hooks {
+				// cpu_instrs doesn't touch cartridge RAM
+				address := uint16(EXTERNAL_RAM_START + i*0x100)
+				gameboy.AddMemoryHook(MemoryHook{
+					Kind:     HOOK_READ | HOOK_WRITE | HOOK_EXECUTE,
+					Start:    address,
+					End:      address,
+					Bank:     -1,
+					Callback: func(access MemoryAccess) {},
+				})
+			}
+
+			b.ResetTimer()
+			for range b.N {
+				target := gameboy.FrameCount() + 1
+				for gameboy.FrameCount() != target {
+					gameboy.Step()
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package goboy
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+type inputEvent struct {
+	button  Button
+	pressed bool
+}
+
+// Button presses can come from any goroutine, but the joypad is only touched
+// by the emulator goroutine, between instructions
+type InputQueue struct {
+	mutex  sync.Mutex
+	events []inputEvent
+	// Set while there are events, so apply doesn't need the lock on every
+	// instruction when nobody is pressing anything
+	pending atomic.Bool
+}
+
+func (queue *InputQueue) push(button Button, pressed bool) {
+	queue.mutex.Lock()
+	queue.events = append(queue.events, inputEvent{button: button, pressed: pressed})
+	queue.pending.Store(true)
+	queue.mutex.Unlock()
+}
+
+func (queue *InputQueue) apply(joypad *Joypad) {
+	if !queue.pending.Load() {
+		return
+	}
+
+	queue.mutex.Lock()
+	events := queue.events
+	queue.events = nil
+	queue.pending.Store(false)
+	queue.mutex.Unlock()
+
+	for _, event := range events {
+		if event.pressed {
+			joypad.Press(event.button)
+		} else {
+			joypad.Release(event.button)
+		}
+	}
+}
@@ -38,7 +38,7 @@ func fetchInstruction(opcode byte) instruction {
 		return instruction
 	}
 
-	panic(fmt.Sprintf("No instruction found for opcode 0x%2.2X", opcode))
+	return invalidInstruction
 }
 
 // todo: don't cry looking at this
@@ -87,10 +87,7 @@ var instructions = [0x100]instruction{
 		ldN8ToR8(cpu, R_C)
 	},
 	0x0F: rrca,
-	0x10: func(cpu *CPU) {
-		// do nothing?
-		// todo: what does STOP actually do?
-	},
+	0x10: stop,
 	0x11: func(cpu *CPU) {
 		ldN16ToR16(cpu, R_DE)
 	},
@@ -140,7 +137,8 @@ var instructions = [0x100]instruction{
 	},
 	0x22: func(cpu *CPU) {
 		ldR8ToMR16(cpu, R_A, R_HL)
-		incR16(cpu, R_HL)
+		// HL is updated during the same cycle as the memory access
+		cpu.registers.write(R_HL, cpu.registers.read(R_HL)+1)
 	},
 	0x23: func(cpu *CPU) {
 		incR16(cpu, R_HL)
@@ -163,7 +161,8 @@ var instructions = [0x100]instruction{
 	},
 	0x2A: func(cpu *CPU) {
 		ldMR16ToR8(cpu, R_HL, R_A)
-		incR16(cpu, R_HL)
+		// HL is updated during the same cycle as the memory access
+		cpu.registers.write(R_HL, cpu.registers.read(R_HL)+1)
 	},
 	0x2B: func(cpu *CPU) {
 		decR16(cpu, R_HL)
@@ -186,7 +185,8 @@ var instructions = [0x100]instruction{
 	},
 	0x32: func(cpu *CPU) {
 		ldR8ToMR16(cpu, R_A, R_HL)
-		decR16(cpu, R_HL)
+		// HL is updated during the same cycle as the memory access
+		cpu.registers.write(R_HL, cpu.registers.read(R_HL)-1)
 	},
 	0x33: func(cpu *CPU) {
 		incR16(cpu, R_SP)
@@ -209,7 +209,8 @@ var instructions = [0x100]instruction{
 	},
 	0x3A: func(cpu *CPU) {
 		ldMR16ToR8(cpu, R_HL, R_A)
-		decR16(cpu, R_HL)
+		// HL is updated during the same cycle as the memory access
+		cpu.registers.write(R_HL, cpu.registers.read(R_HL)-1)
 	},
 	0x3B: func(cpu *CPU) {
 		decR16(cpu, R_SP)
@@ -226,6 +227,8 @@ var instructions = [0x100]instruction{
 	0x3F: ccf,
 	0x40: func(cpu *CPU) {
 		ldR8ToR8(cpu, R_B, R_B)
+		// LD B, B does nothing, so emulators and test ROMs use it as a breakpoint
+		cpu.gameboy.onSoftwareBreakpoint()
 	},
 	0x41: func(cpu *CPU) {
 		ldR8ToR8(cpu, R_C, R_B)
@@ -386,9 +389,7 @@ var instructions = [0x100]instruction{
 	0x75: func(cpu *CPU) {
 		ldR8ToMR16(cpu, R_L, R_HL)
 	},
-	0x76: func(cpu *CPU) {
-		cpu.halted = true
-	},
+	0x76: halt,
 	0x77: func(cpu *CPU) {
 		ldR8ToMR16(cpu, R_A, R_HL)
 	},
@@ -618,8 +619,7 @@ var instructions = [0x100]instruction{
 		jpA16(cpu, C_NZ)
 	},
 	0xC3: func(cpu *CPU) {
-		nextAddress := readWordFromPC(cpu)
-		cpu.registers.write(R_PC, nextAddress)
+		jpA16(cpu, C_ANY)
 	},
 	0xC4: func(cpu *CPU) {
 		call(cpu, C_NZ)
@@ -775,17 +775,16 @@ func readWordFromPC(cpu *CPU) uint16 {
 
 // @see https://gist.github.com/SonoSooS/c0055300670d678b5ae8433e20bea595#opcode-holes-not-implemented-opcodes
 func invalidInstruction(cpu *CPU) {
-	// panic("this crashes the game boy")
+	cpu.lockUp(cpu.opcodeAddress, cpu.opcode)
 }
 
 func ldR8ToR8(cpu *CPU, src CpuRegister, dest CpuRegister) {
 	cpu.registers.write(dest, cpu.registers.read(src))
-	cpu.gameboy.Cycle(1)
 }
 
 func ldR16ToR16(cpu *CPU, src CpuRegister, dest CpuRegister) {
 	cpu.registers.write(dest, cpu.registers.read(src))
-	cpu.gameboy.Cycle(2)
+	cpu.idle()
 }
 
 func ldR16E8ToR16(cpu *CPU, src CpuRegister, dest CpuRegister) {
@@ -802,88 +801,74 @@ func ldR16E8ToR16(cpu *CPU, src CpuRegister, dest CpuRegister) {
 		((reg&0x0F)+(uint16(addend)&0x0F)) > 0x0F,
 		((reg&0xFF)+(uint16(addend)&0xFF)) > 0xFF,
 	)
-	cpu.gameboy.Cycle(3)
+	cpu.idle()
 }
 
 func ldR8ToMR16(cpu *CPU, src CpuRegister, dest CpuRegister) {
-	cpu.bus.writeByte(cpu.registers.read(dest), byte(cpu.registers.read(src)))
-	cpu.gameboy.Cycle(2)
+	cpu.writeByte(cpu.registers.read(dest), byte(cpu.registers.read(src)))
 }
 
 func ldR16ToA16(cpu *CPU, src CpuRegister) {
 	value := cpu.registers.read(src)
 	address := readWordFromPC(cpu)
 
-	cpu.bus.writeByte(address, byte(value&0xFF))
-	cpu.bus.writeByte(address+1, byte(value>>8))
-
-	cpu.gameboy.Cycle(5)
+	cpu.writeByte(address, byte(value&0xFF))
+	cpu.writeByte(address+1, byte(value>>8))
 }
 
 func ldR8ToMR8(cpu *CPU, src CpuRegister, dest CpuRegister) {
-	cpu.bus.writeByte(0xFF00+cpu.registers.read(dest), byte(cpu.registers.read(src)))
-	cpu.gameboy.Cycle(2)
+	cpu.writeByte(0xFF00+cpu.registers.read(dest), byte(cpu.registers.read(src)))
 }
 
 func ldMR16ToR8(cpu *CPU, src CpuRegister, dest CpuRegister) {
 	address := cpu.registers.read(src)
-	value := cpu.bus.readByte(address)
+	value := cpu.readByte(address)
 	cpu.registers.write(dest, uint16(value))
-	cpu.gameboy.Cycle(2)
 }
 
 func ldMR8ToR8(cpu *CPU, src CpuRegister, dest CpuRegister) {
 	address := 0xFF00 + cpu.registers.read(src)
-	value := uint16(cpu.bus.readByte(address))
+	value := uint16(cpu.readByte(address))
 	cpu.registers.write(dest, value)
-	cpu.gameboy.Cycle(2)
 }
 
 func ldN16ToR16(cpu *CPU, dest CpuRegister) {
 	n16 := readWordFromPC(cpu)
 	cpu.registers.write(dest, n16)
-	cpu.gameboy.Cycle(3)
 }
 
 func ldN8ToR8(cpu *CPU, dest CpuRegister) {
 	n8 := readByteFromPC(cpu)
 	cpu.registers.write(dest, uint16(n8))
-	cpu.gameboy.Cycle(2)
 }
 
 func ldN8ToMR16(cpu *CPU, dest CpuRegister) {
 	n8 := readByteFromPC(cpu)
 	address := cpu.registers.read(dest)
-	cpu.bus.writeByte(address, n8)
-	cpu.gameboy.Cycle(3)
+	cpu.writeByte(address, n8)
 }
 
 func ldR8ToN16(cpu *CPU, src CpuRegister) {
 	dest := readWordFromPC(cpu)
-	cpu.bus.writeByte(dest, byte(cpu.registers.read(src)))
-	cpu.gameboy.Cycle(4)
+	cpu.writeByte(dest, byte(cpu.registers.read(src)))
 }
 
 func ldA16ToR8(cpu *CPU, dest CpuRegister) {
 	a16 := readWordFromPC(cpu)
-	value := cpu.bus.readByte(a16)
+	value := cpu.readByte(a16)
 	cpu.registers.write(dest, uint16(value))
-	cpu.gameboy.Cycle(4)
 }
 
 func xorR8(cpu *CPU, src CpuRegister) {
 	xor(cpu, byte(cpu.registers.read(src)))
-	cpu.gameboy.Cycle(1)
 }
 
 func xorMR16(cpu *CPU, src CpuRegister) {
-	xor(cpu, cpu.bus.readByte(cpu.registers.read(src)))
-	cpu.gameboy.Cycle(2)
+	xor(cpu, cpu.readByte(cpu.registers.read(src)))
 }
 
 func xorN8(cpu *CPU) {
 	xor(cpu, readByteFromPC(cpu))
-	cpu.gameboy.Cycle(2)
 }
 
 func xor(cpu *CPU, comparator byte) {
@@ -901,24 +886,21 @@ func incR8(cpu *CPU, reg CpuRegister) {
 	cpu.registers.setFlag(FLAG_Z, result == 0)
 	cpu.registers.setFlag(FLAG_N, false)
 	cpu.registers.setFlag(FLAG_H, (result&0x0F) == 0)
-
-	cpu.gameboy.Cycle(1)
 }
 
 func incR16(cpu *CPU, reg CpuRegister) {
 	cpu.registers.write(reg, cpu.registers.read(reg)+1)
-	cpu.gameboy.Cycle(2)
+	cpu.idle()
 }
 
 func incMR16(cpu *CPU, reg CpuRegister) {
 	address := cpu.registers.read(reg)
-	result := cpu.bus.readByte(address) + 1
-	cpu.bus.writeByte(address, result)
+	result := cpu.readByte(address) + 1
+	cpu.writeByte(address, result)
 
 	cpu.registers.setFlag(FLAG_Z, result == 0)
 	cpu.registers.setFlag(FLAG_N, false)
 	cpu.registers.setFlag(FLAG_H, (result&0x0F) == 0)
-	cpu.gameboy.Cycle(3)
 }
 
 func decR8(cpu *CPU, reg CpuRegister) {
@@ -928,76 +910,64 @@ func decR8(cpu *CPU, reg CpuRegister) {
 	cpu.registers.setFlag(FLAG_Z, result == 0)
 	cpu.registers.setFlag(FLAG_N, true)
 	cpu.registers.setFlag(FLAG_H, (result&0x0F) == 0x0F)
-
-	cpu.gameboy.Cycle(1)
 }
 
 func decR16(cpu *CPU, reg CpuRegister) {
 	cpu.registers.write(reg, cpu.registers.read(reg)-1)
-
-	cpu.gameboy.Cycle(2)
+	cpu.idle()
 }
 
 func decMR16(cpu *CPU, reg CpuRegister) {
 	address := cpu.registers.read(reg)
-	result := cpu.bus.readByte(address) - 1
-	cpu.bus.writeByte(address, result)
+	result := cpu.readByte(address) - 1
+	cpu.writeByte(address, result)
 
 	cpu.registers.setFlag(FLAG_Z, result == 0)
 	cpu.registers.setFlag(FLAG_N, true)
 	cpu.registers.setFlag(FLAG_H, (result&0x0F) == 0x0F)
-
-	cpu.gameboy.Cycle(3)
 }
 
 func jr(cpu *CPU, cond condition) {
 	e8 := readByteFromPC(cpu)
 
 	if !checkCondition(cpu, cond) {
-		cpu.gameboy.Cycle(2)
 		return
 	}
 
 	offset := uint16(int8(e8))
 	nextAddress := cpu.registers.read(R_PC) + offset
 	cpu.registers.write(R_PC, nextAddress)
-
-	cpu.gameboy.Cycle(3)
+	cpu.idle()
 }
 
 func ldhR8ToA8(cpu *CPU, src CpuRegister) {
 	a8 := readByteFromPC(cpu)
 	address := 0xFF00 + uint16(a8)
-	cpu.bus.writeByte(address, byte(cpu.registers.read(src)))
-	cpu.gameboy.Cycle(3)
+	cpu.writeByte(address, byte(cpu.registers.read(src)))
 }
 
 func ldhA8ToR8(cpu *CPU, dest CpuRegister) {
 	a8 := readByteFromPC(cpu)
 	address := 0xFF00 + uint16(a8)
-	cpu.registers.write(dest, uint16(cpu.bus.readByte(address)))
-	cpu.gameboy.Cycle(3)
+	cpu.registers.write(dest, uint16(cpu.readByte(address)))
 }
 
 func cpN8(cpu *CPU) {
 	minuend := byte(cpu.registers.read(R_A))
 	subtrahend := readByteFromPC(cpu)
 	cp(cpu, minuend, subtrahend)
-	cpu.gameboy.Cycle(2)
 }
 
 func cpR8(cpu *CPU, src CpuRegister) {
 	minuend := byte(cpu.registers.read(R_A))
 	subtrahend := byte(cpu.registers.read(src))
 	cp(cpu, minuend, subtrahend)
-	cpu.gameboy.Cycle(1)
 }
 
 func cpMR8(cpu *CPU, src CpuRegister) {
 	minuend := byte(cpu.registers.read(R_A))
-	subtrahend := cpu.bus.readByte(cpu.registers.read(src))
+	subtrahend := cpu.readByte(cpu.registers.read(src))
 	cp(cpu, minuend, subtrahend)
-	cpu.gameboy.Cycle(2)
 }
 
 func cp(cpu *CPU, minuend byte, subtrahend byte) {
@@ -1022,8 +992,6 @@ func addR8(cpu *CPU, src CpuRegister) {
 		((a&0x0F)+(addend&0x0F)) > 0x0F,
 		result > 0xFF,
 	)
-
-	cpu.gameboy.Cycle(1)
 }
 
 func addR16(cpu *CPU, src CpuRegister) {
@@ -1036,13 +1004,12 @@ func addR16(cpu *CPU, src CpuRegister) {
 	cpu.registers.setFlag(FLAG_N, false)
 	cpu.registers.setFlag(FLAG_H, ((hl&0x0FFF)+(addend&0x0FFF)) > 0x0FFF)
 	cpu.registers.setFlag(FLAG_C, uint32(hl)+uint32(addend) > 0xFFFF)
-
-	cpu.gameboy.Cycle(2)
+	cpu.idle()
 }
 
 func addMR16(cpu *CPU, src CpuRegister) {
 	a := cpu.registers.read(R_A)
-	addend := uint16(cpu.bus.readByte(cpu.registers.read(src)))
+	addend := uint16(cpu.readByte(cpu.registers.read(src)))
 	result := a + addend
 
 	cpu.registers.write(R_A, result)
@@ -1053,8 +1020,6 @@ func addMR16(cpu *CPU, src CpuRegister) {
 		((a&0x0F)+(addend&0x0F)) > 0x0F,
 		result > 0xFF,
 	)
-
-	cpu.gameboy.Cycle(2)
 }
 
 func addN8A(cpu *CPU) {
@@ -1070,8 +1035,6 @@ func addN8A(cpu *CPU) {
 		((a&0x0F)+(addend&0x0F)) > 0x0F,
 		result > 0xFF,
 	)
-
-	cpu.gameboy.Cycle(2)
 }
 
 func addN8SP(cpu *CPU) {
@@ -1087,23 +1050,20 @@ func addN8SP(cpu *CPU) {
 		((sp&0x0F)+(uint16(addend)&0x0F)) > 0x0F,
 		((sp&0xFF)+(uint16(addend)&0xFF)) > 0xFF,
 	)
-
-	cpu.gameboy.Cycle(4)
+	cpu.idle()
+	cpu.idle()
 }
 
 func adcR8(cpu *CPU, src CpuRegister) {
 	adc(cpu, cpu.registers.read(src))
-	cpu.gameboy.Cycle(1)
 }
 
 func adcMR16(cpu *CPU, src CpuRegister) {
-	adc(cpu, uint16(cpu.bus.readByte(cpu.registers.read(src))))
-	cpu.gameboy.Cycle(2)
+	adc(cpu, uint16(cpu.readByte(cpu.registers.read(src))))
 }
 
 func adcN8(cpu *CPU) {
 	adc(cpu, uint16(readByteFromPC(cpu)))
-	cpu.gameboy.Cycle(2)
 }
 
 func adc(cpu *CPU, addend uint16) {
@@ -1123,17 +1083,14 @@ func adc(cpu *CPU, addend uint16) {
 
 func subR8(cpu *CPU, src CpuRegister) {
 	sub(cpu, cpu.registers.read(src))
-	cpu.gameboy.Cycle(1)
 }
 
 func subMR16(cpu *CPU, src CpuRegister) {
-	sub(cpu, uint16(cpu.bus.readByte(cpu.registers.read(src))))
-	cpu.gameboy.Cycle(2)
+	sub(cpu, uint16(cpu.readByte(cpu.registers.read(src))))
 }
 
 func subN8(cpu *CPU) {
 	sub(cpu, uint16(readByteFromPC(cpu)))
-	cpu.gameboy.Cycle(2)
 }
 
 func sub(cpu *CPU, subtrahend uint16) {
@@ -1152,17 +1109,14 @@ func sub(cpu *CPU, subtrahend uint16) {
 
 func sbcR8(cpu *CPU, src CpuRegister) {
 	sbc(cpu, byte(cpu.registers.read(src)))
-	cpu.gameboy.Cycle(1)
 }
 
 func sbcMR16(cpu *CPU, src CpuRegister) {
-	sbc(cpu, cpu.bus.readByte(cpu.registers.read(src)))
-	cpu.gameboy.Cycle(2)
+	sbc(cpu, cpu.readByte(cpu.registers.read(src)))
 }
 
 func sbcN8(cpu *CPU) {
 	sbc(cpu, readByteFromPC(cpu))
-	cpu.gameboy.Cycle(2)
 }
 
 func sbc(cpu *CPU, subtrahend byte) {
@@ -1182,17 +1136,14 @@ func sbc(cpu *CPU, subtrahend byte) {
 
 func andR8(cpu *CPU, src CpuRegister) {
 	and(cpu, byte(cpu.registers.read(src)))
-	cpu.gameboy.Cycle(1)
 }
 
 func andMR16(cpu *CPU, src CpuRegister) {
-	and(cpu, cpu.bus.readByte(cpu.registers.read(src)))
-	cpu.gameboy.Cycle(2)
+	and(cpu, cpu.readByte(cpu.registers.read(src)))
 }
 
 func andN8(cpu *CPU) {
 	and(cpu, readByteFromPC(cpu))
-	cpu.gameboy.Cycle(2)
 }
 
 func and(cpu *CPU, comparator byte) {
@@ -1205,17 +1156,14 @@ func and(cpu *CPU, comparator byte) {
 
 func orR8(cpu *CPU, src CpuRegister) {
 	or(cpu, byte(cpu.registers.read(src)))
-	cpu.gameboy.Cycle(1)
 }
 
 func orMR16(cpu *CPU, src CpuRegister) {
-	or(cpu, cpu.bus.readByte(cpu.registers.read(src)))
-	cpu.gameboy.Cycle(2)
+	or(cpu, cpu.readByte(cpu.registers.read(src)))
 }
 
 func orN8(cpu *CPU) {
 	or(cpu, readByteFromPC(cpu))
-	cpu.gameboy.Cycle(2)
 }
 
 func or(cpu *CPU, comparator byte) {
@@ -1234,8 +1182,6 @@ func rlca(cpu *CPU) {
 
 	cpu.registers.write(R_A, uint16(a))
 	cpu.registers.setFlags(false, false, false, c)
-
-	cpu.gameboy.Cycle(1)
 }
 
 func rrca(cpu *CPU) {
@@ -1246,8 +1192,6 @@ func rrca(cpu *CPU) {
 
 	cpu.registers.write(R_A, uint16(a))
 	cpu.registers.setFlags(false, false, false, c)
-
-	cpu.gameboy.Cycle(1)
 }
 
 func rla(cpu *CPU) {
@@ -1259,8 +1203,6 @@ func rla(cpu *CPU) {
 
 	cpu.registers.write(R_A, uint16(a))
 	cpu.registers.setFlags(false, false, false, msb == 1)
-
-	cpu.gameboy.Cycle(1)
 }
 
 func rra(cpu *CPU) {
@@ -1272,8 +1214,6 @@ func rra(cpu *CPU) {
 
 	cpu.registers.write(R_A, uint16(a))
 	cpu.registers.setFlags(false, false, false, lsb == 1)
-
-	cpu.gameboy.Cycle(1)
 }
 
 // The DAA (Decimal Adjust Accumulator) instruction is used to adjust the
@@ -1310,8 +1250,6 @@ func daa(cpu *CPU) {
 
 	cpu.registers.write(R_A, uint16(a))
 	cpu.registers.setFlags(a == 0, n, false, c)
-
-	cpu.gameboy.Cycle(1)
 }
 
 func cpl(cpu *CPU) {
@@ -1320,95 +1258,120 @@ func cpl(cpu *CPU) {
 	cpu.registers.write(R_A, r)
 	cpu.registers.setFlag(FLAG_N, true)
 	cpu.registers.setFlag(FLAG_H, true)
-
-	cpu.gameboy.Cycle(1)
 }
 
 func scf(cpu *CPU) {
 	cpu.registers.setFlag(FLAG_N, false)
 	cpu.registers.setFlag(FLAG_H, false)
 	cpu.registers.setFlag(FLAG_C, true)
-
-	cpu.gameboy.Cycle(1)
 }
 
 func ccf(cpu *CPU) {
 	cpu.registers.setFlag(FLAG_N, false)
 	cpu.registers.setFlag(FLAG_H, false)
 	cpu.registers.setFlag(FLAG_C, !cpu.registers.readFlag(FLAG_C))
-
-	cpu.gameboy.Cycle(1)
 }
 
 func push(cpu *CPU, src CpuRegister) {
-	r := cpu.registers.read(src)
-	hi, lo := Uint16ToBytes(r)
+	cpu.idle()
+	pushWord(cpu, cpu.registers.read(src))
+}
+
+func pushWord(cpu *CPU, value uint16) {
+	hi, lo := Uint16ToBytes(value)
+	sp := cpu.registers.read(R_SP)
 
-	decR16(cpu, R_SP)
-	cpu.bus.writeByte(cpu.registers.read(R_SP), hi)
-	decR16(cpu, R_SP)
-	cpu.bus.writeByte(cpu.registers.read(R_SP), lo)
+	cpu.writeByte(sp-1, hi)
+	cpu.writeByte(sp-2, lo)
+	cpu.registers.write(R_SP, sp-2)
 }
 
 func pop(cpu *CPU, hiDest CpuRegister, loDest CpuRegister) {
-	ldMR16ToR8(cpu, R_SP, loDest)
-	incR16(cpu, R_SP)
+	sp := cpu.registers.read(R_SP)
 
-	ldMR16ToR8(cpu, R_SP, hiDest)
-	incR16(cpu, R_SP)
+	cpu.registers.write(loDest, uint16(cpu.readByte(sp)))
+	cpu.registers.write(hiDest, uint16(cpu.readByte(sp+1)))
+	cpu.registers.write(R_SP, sp+2)
 }
 
 func jpA16(cpu *CPU, cond condition) {
 	nextAddress := readWordFromPC(cpu)
 
 	if !checkCondition(cpu, cond) {
-		cpu.gameboy.Cycle(3)
 		return
 	}
 
 	cpu.registers.write(R_PC, nextAddress)
-	cpu.gameboy.Cycle(4)
+	cpu.idle()
 }
 
 func jpR16(cpu *CPU, src CpuRegister) {
 	cpu.registers.write(R_PC, cpu.registers.read(src))
-	cpu.gameboy.Cycle(1)
 }
 
 func call(cpu *CPU, cond condition) {
 	address := readWordFromPC(cpu)
 
 	if !checkCondition(cpu, cond) {
-		cpu.gameboy.Cycle(3)
 		return
 	}
 
-	pc := cpu.registers.read(R_PC)
-	hi, lo := Uint16ToBytes(pc)
-
-	decR16(cpu, R_SP)
-	cpu.bus.writeByte(cpu.registers.read(R_SP), hi)
-	decR16(cpu, R_SP)
-	cpu.bus.writeByte(cpu.registers.read(R_SP), lo)
-
+	cpu.idle()
+	pushWord(cpu, cpu.registers.read(R_PC))
 	cpu.registers.write(R_PC, address)
-	cpu.gameboy.Cycle(2)
 }
 
 func ret(cpu *CPU, cond condition) {
+	// Unlike JP and CALL, checking the condition takes a cycle of its own
+	if cond != C_ANY {
+		cpu.idle()
+	}
+
 	if !checkCondition(cpu, cond) {
-		cpu.gameboy.Cycle(2)
 		return
 	}
 
-	lo := cpu.bus.readByte(cpu.registers.read(R_SP))
-	incR16(cpu, R_SP)
-
-	hi := cpu.bus.readByte(cpu.registers.read(R_SP))
-	incR16(cpu, R_SP)
+	sp := cpu.registers.read(R_SP)
+	lo := cpu.readByte(sp)
+	hi := cpu.readByte(sp + 1)
 
+	cpu.registers.write(R_SP, sp+2)
 	cpu.registers.write(R_PC, BytesToUint16(hi, lo))
-	cpu.gameboy.Cycle(1)
+	cpu.idle()
+}
+
+// STOP's behaviour depends on whether a button is held and an interrupt is
+// pending. It usually skips the byte after it and stops everything, including
+// DIV and the LCD, until a button is pressed.
+// @see https://gbdev.io/pandocs/Reducing_Power_Consumption.html#using-the-stop-instruction
+func stop(cpu *CPU) {
+	buttonHeld := cpu.gameboy.joypad.readByte(IO_JOYP)&0x0F != 0x0F
+	interruptPending := cpu.pendingInterrupts() != 0
+
+	if !interruptPending {
+		pc := cpu.registers.read(R_PC)
+		cpu.registers.write(R_PC, pc+1)
+	}
+
+	if buttonHeld {
+		if !interruptPending {
+			cpu.halted = true
+		}
+		return
+	}
+
+	cpu.bus.pokeByte(TIMER_DIV, 0)
+	cpu.stopped = true
+}
+
+// @see https://gbdev.io/pandocs/halt.html
+func halt(cpu *CPU) {
+	if !cpu.interruptMasterEnabled && cpu.pendingInterrupts() != 0 {
+		cpu.haltBug = true
+		return
+	}
+
+	cpu.halted = true
 }
 
 func rst(cpu *CPU, address uint16) {
@@ -1427,35 +1390,19 @@ func prefix(cpu *CPU) {
 	bit := (opcode >> 3) & 0b111
 	bitOperation := (opcode >> 6) & 0b11
 
-	if register == R_HL {
-		cpu.gameboy.Cycle(3)
-	} else {
-		cpu.gameboy.Cycle(2)
-	}
-
 	switch bitOperation {
 	case 1:
 		cbBit(cpu, register, bit)
 	case 2:
 		cbRes(cpu, register, bit)
-		if register == R_HL {
-			cpu.gameboy.Cycle(1)
-		}
 	case 3:
 		cbSet(cpu, register, bit)
-		if register == R_HL {
-			cpu.gameboy.Cycle(1)
-		}
 	}
 
 	if bitOperation != 0 {
 		return
 	}
 
-	if register == R_HL {
-		cpu.gameboy.Cycle(1)
-	}
-
 	switch bit {
 	case 0:
 		rlc(cpu, register)
@@ -1480,14 +1427,14 @@ func prefix(cpu *CPU) {
 
 func cbReadData(cpu *CPU, src CpuRegister) byte {
 	if src == R_HL {
-		return cpu.bus.readByte(cpu.registers.read(src))
+		return cpu.readByte(cpu.registers.read(src))
 	}
 	return byte(cpu.registers.read(src))
 }
 
 func cbWriteData(cpu *CPU, dest CpuRegister, value byte) {
 	if dest == R_HL {
-		cpu.bus.writeByte(cpu.registers.read(dest), value)
+		cpu.writeByte(cpu.registers.read(dest), value)
 	} else {
 		cpu.registers.write(dest, uint16(value))
 	}
@@ -2,10 +2,15 @@ package goboy
 
 import (
 	"encoding/json"
+	"flag"
+	"fmt"
 	"os"
 	"path"
 	"runtime"
+	"slices"
 	"testing"
+
+	"github.com/seashairo/goboy/internal/disasm"
 )
 
 func TestInstructions_00(t *testing.T) { testFile(t, "00.json") }
@@ -221,7 +226,6 @@ func TestInstructions_c7(t *testing.T) { testFile(t, "c7.json") }
 func TestInstructions_c8(t *testing.T) { testFile(t, "c8.json") }
 func TestInstructions_c9(t *testing.T) { testFile(t, "c9.json") }
 func TestInstructions_ca(t *testing.T) { testFile(t, "ca.json") }
-func TestInstructions_cb(t *testing.T) { testFile(t, "cb.json") }
 func TestInstructions_cc(t *testing.T) { testFile(t, "cc.json") }
 func TestInstructions_cd(t *testing.T) { testFile(t, "cd.json") }
 func TestInstructions_ce(t *testing.T) { testFile(t, "ce.json") }
@@ -265,9 +269,168 @@ func TestInstructions_fa(t *testing.T) { testFile(t, "fa.json") }
 func TestInstructions_fe(t *testing.T) { testFile(t, "fe.json") }
 func TestInstructions_ff(t *testing.T) { testFile(t, "ff.json") }
 
+var cbTests = flag.Bool("cb", false, "run the 0xCB instruction tests, which need cb 00.json to cb ff.json in data/test_data")
+
+// 0xCB is the prefix for another 256 instructions, which each have their own
+// file. They aren't in the repo, so these only run with -cb, and then a missing
+// file fails rather than being skipped.
+func TestInstructions_cb(t *testing.T) {
+	if !*cbTests {
+		t.Skip("run with -cb to test against the 0xCB test data")
+	}
+
+	for opcode := 0; opcode <= 0xFF; opcode++ {
+		filename := fmt.Sprintf("cb %2.2x.json", opcode)
+
+		t.Run(filename, func(t *testing.T) {
+			if _, err := os.Stat("data/test_data/" + filename); err != nil {
+				t.Fatalf("no test data at data/test_data/%s, copy it in from https://github.com/adtennant/GameboyCPUTests", filename)
+			}
+
+			testFile(t, filename)
+		})
+	}
+}
+
+// Until the 0xCB test data is in the repo, check every 0xCB instruction's
+// result, flags and bus accesses against Pan Docs. Register operands take 2
+// M-cycles, the two opcode fetches. (HL) operands read (HL) in a third, and
+// everything but BIT writes the result back in a fourth.
+//
+// @see https://gbdev.io/pandocs/CPU_Instruction_Set.html#cb-prefix-instructions
+// @see https://gbdev.io/gb-opcodes/optables/
+func TestInstructions_cbSpec(t *testing.T) {
+	const address = WRAM_TEST_PROGRAM_START + 0x100
+	sameAccess := func(a *busAccess, b *busAccess) bool {
+		return a == b || (a != nil && b != nil && *a == *b)
+	}
+
+	for opcode := 0; opcode <= 0xFF; opcode++ {
+		gameboy := newWramTestGameBoy(t, []byte{0xCB, byte(opcode)}, false)
+		registers := gameboy.cpu.registers
+		operands := [8]*byte{&registers.b, &registers.c, &registers.d, &registers.e, &registers.h, &registers.l, nil, &registers.a}
+		operand := operands[opcode&7]
+
+		instruction := disasm.Disassemble(gameboy.bus.readByte, WRAM_TEST_PROGRAM_START)
+
+		for _, value := range []byte{0x00, 0x01, 0x80, 0x85, 0xFF} {
+			for _, carry := range []bool{false, true} {
+				expected, flags, writes := cbReference(byte(opcode), value, carry)
+
+				*registers = CpuRegisters{pc: WRAM_TEST_PROGRAM_START, sp: 0xDFF0}
+				registers.write(R_HL, address)
+				registers.f = SetBit(0, 4, carry)
+				if operand == nil {
+					gameboy.bus.writeByte(address, value)
+				} else {
+					*operand = value
+				}
+
+				cycles := []*busAccess{}
+				gameboy.cpu.onMCycle = func(access *busAccess) {
+					cycles = append(cycles, access)
+				}
+				gameboy.Step()
+				gameboy.cpu.onMCycle = nil
+
+				expectedCycles := []*busAccess{
+					{address: WRAM_TEST_PROGRAM_START, value: 0xCB},
+					{address: WRAM_TEST_PROGRAM_START + 1, value: byte(opcode)},
+				}
+
+				actual := gameboy.bus.readByte(address)
+				if operand != nil {
+					actual = *operand
+				} else {
+					expectedCycles = append(expectedCycles, &busAccess{address: address, value: value})
+					if writes {
+						expectedCycles = append(expectedCycles, &busAccess{address: address, value: expected, write: true})
+					}
+				}
+
+				name := fmt.Sprintf("%s with 0x%2.2X, carry %t", instruction, value, carry)
+				if actual != expected {
+					t.Errorf("%s: expected 0x%2.2X, got 0x%2.2X", name, expected, actual)
+				}
+				if registers.f != flags {
+					t.Errorf("%s: expected flags 0x%2.2X, got 0x%2.2X", name, flags, registers.f)
+				}
+
+				if !slices.EqualFunc(cycles, expectedCycles, sameAccess) {
+					t.Errorf("%s: expected bus accesses %v, got %v", name, expectedCycles, cycles)
+				}
+
+				if instruction.Cycles != len(expectedCycles) {
+					t.Errorf("Disassembler: expected %s to take %d M-cycles, it says %d", instruction, len(expectedCycles), instruction.Cycles)
+				}
+			}
+		}
+	}
+}
+
+// What a 0xCB instruction does to its operand and the flags, straight from
+// Pan Docs rather than from the CPU, and whether it writes the operand back
+func cbReference(opcode byte, value byte, carry bool) (byte, byte, bool) {
+	bit := (opcode >> 3) & 7
+	carryIn := byte(0)
+	if carry {
+		carryIn = 1
+	}
+
+	var result byte
+	var carryOut bool
+	switch opcode >> 6 {
+	case 0:
+		switch bit {
+		case 0: // RLC
+			result, carryOut = value<<1|value>>7, value&0x80 != 0
+		case 1: // RRC
+			result, carryOut = value>>1|value<<7, value&0x01 != 0
+		case 2: // RL
+			result, carryOut = value<<1|carryIn, value&0x80 != 0
+		case 3: // RR
+			result, carryOut = value>>1|carryIn<<7, value&0x01 != 0
+		case 4: // SLA
+			result, carryOut = value<<1, value&0x80 != 0
+		case 5: // SRA
+			result, carryOut = value>>1|value&0x80, value&0x01 != 0
+		case 6: // SWAP
+			result, carryOut = value<<4|value>>4, false
+		case 7: // SRL
+			result, carryOut = value>>1, value&0x01 != 0
+		}
+
+		flags := SetBit(0, 7, result == 0)
+		flags = SetBit(flags, 4, carryOut)
+		return result, flags, true
+	case 1: // BIT
+		flags := SetBit(0, 7, value&(1<<bit) == 0)
+		flags = SetBit(flags, 5, true)
+		flags = SetBit(flags, 4, carry)
+		return value, flags, false
+	case 2: // RES
+		return value &^ (1 << bit), SetBit(0, 4, carry), true
+	default: // SET
+		return value | 1<<bit, SetBit(0, 4, carry), true
+	}
+}
+
+// The JSON tests treat the whole address space as RAM
+type flatBus struct {
+	*RAM
+}
+
+func (bus flatBus) peekByte(address uint16) byte {
+	return bus.readByte(address)
+}
+
+func (bus flatBus) pokeByte(address uint16, value byte) {
+	bus.writeByte(address, value)
+}
+
 func testFile(t *testing.T, filename string) {
-	gameboy := NewGameBoy()
-	gameboy.bus = NewRAM(0x10000, 0)
+	gameboy := newTestGameBoy(t)
+	gameboy.bus = flatBus{NewRAM(0x10000, 0)}
 	gameboy.cpu.bus = gameboy.bus
 
 	testCases := loadJson(t, filename)
@@ -290,6 +453,8 @@ func executeTest(t *testing.T, gameboy *GameBoy, testCase TestCase) {
 	registers.e = init.E
 	registers.h = init.H
 	registers.l = init.L
+	// The tests start with the opcode already fetched, as the CPU fetches the
+	// next opcode during the last M-cycle of each instruction
 	registers.pc = init.PC - 1
 	registers.sp = init.SP
 
@@ -300,7 +465,20 @@ func executeTest(t *testing.T, gameboy *GameBoy, testCase TestCase) {
 		gameboy.bus.writeByte(address, byte(value))
 	}
 
+	instruction := disasm.Disassemble(gameboy.bus.readByte, init.PC-1)
+
+	cycles := []*busAccess{}
+	gameboy.cpu.onMCycle = func(access *busAccess) {
+		cycles = append(cycles, access)
+	}
+	defer func() { gameboy.cpu.onMCycle = nil }()
+
+	start := gameboy.cycles
 	gameboy.cpu.Tick()
+	mCycles := int(gameboy.cycles - start)
+
+	// Fetch the next opcode, so PC and the bus accesses line up with the tests
+	gameboy.cpu.fetchNextOpcode()
 
 	// Get the expected final state
 	final := testCase.Final
@@ -332,8 +510,8 @@ func executeTest(t *testing.T, gameboy *GameBoy, testCase TestCase) {
 	}
 
 	// Compare final PC and SP values
-	if registers.pc != final.PC-1 {
-		t.Errorf("Program Counter: expected %d, got %d", final.PC-1, registers.pc)
+	if registers.pc != final.PC {
+		t.Errorf("Program Counter: expected %d, got %d", final.PC, registers.pc)
 	}
 	if registers.sp != final.SP {
 		t.Errorf("Stack Pointer: expected %d, got %d", final.SP, registers.sp)
@@ -348,6 +526,39 @@ func executeTest(t *testing.T, gameboy *GameBoy, testCase TestCase) {
 			t.Errorf("RAM at address 0x%04X: expected %d, got %d", address, expectedValue, actualValue)
 		}
 	}
+
+	// Each entry in the test's cycles is one M-cycle, and is null when the CPU
+	// doesn't touch the bus
+	if mCycles != len(testCase.Cycles) {
+		t.Errorf("M-cycles: expected %d, got %d", len(testCase.Cycles), mCycles)
+	}
+
+	// The disassembler's timings should agree with the tests too
+	if len(testCase.Cycles) != instruction.Cycles && len(testCase.Cycles) != instruction.CyclesNotTaken {
+		t.Errorf("Disassembler: expected %s to take %d M-cycles, it says %d/%d", instruction, len(testCase.Cycles), instruction.Cycles, instruction.CyclesNotTaken)
+	}
+
+	expectedCycles := []*busAccess{}
+	for _, cycle := range testCase.Cycles {
+		if cycle == nil {
+			expectedCycles = append(expectedCycles, nil)
+		} else {
+			expectedCycles = append(expectedCycles, &cycle.access)
+		}
+	}
+
+	// The first cycle is the fetch of this instruction's opcode, which the
+	// tests count as part of the previous instruction
+	if len(cycles) == 0 || cycles[0] == nil || cycles[0].write || cycles[0].address != init.PC-1 {
+		t.Fatalf("Bus accesses: expected the opcode to be read from 0x%04X first, got %v", init.PC-1, cycles)
+	}
+
+	sameAccess := func(a *busAccess, b *busAccess) bool {
+		return a == b || (a != nil && b != nil && *a == *b)
+	}
+	if !slices.EqualFunc(cycles[1:], expectedCycles, sameAccess) {
+		t.Errorf("Bus accesses: expected %v, got %v", expectedCycles, cycles[1:])
+	}
 }
 
 type State struct {
@@ -365,10 +576,32 @@ type State struct {
 }
 
 type TestCase struct {
-	Name    string          `json:"name"`
-	Initial State           `json:"initial"`
-	Final   State           `json:"final"`
-	Cycles  [][]interface{} `json:"cycles"`
+	Name    string   `json:"name"`
+	Initial State    `json:"initial"`
+	Final   State    `json:"final"`
+	Cycles  []*Cycle `json:"cycles"`
+}
+
+// A single M-cycle, decoded from [address, value, "read" | "write"]
+type Cycle struct {
+	access busAccess
+}
+
+func (cycle *Cycle) UnmarshalJSON(data []byte) error {
+	var fields [3]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+
+	address, addressOk := fields[0].(float64)
+	value, valueOk := fields[1].(float64)
+	kind, kindOk := fields[2].(string)
+	if !addressOk || !valueOk || !kindOk || (kind != "read" && kind != "write") {
+		return fmt.Errorf("invalid cycle %s", data)
+	}
+
+	cycle.access = busAccess{address: uint16(address), value: byte(value), write: kind == "write"}
+	return nil
 }
 
 func loadJson(t *testing.T, filename string) []TestCase {
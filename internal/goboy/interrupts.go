@@ -30,47 +30,50 @@ func (ie *InterruptRegister) writeByte(value byte) {
 	ie.data = value
 }
 
-func (cpu *CPU) handleInterrupts() {
-	if cpu.handleInterrupt(INT_VBLANK, 0x40) {
-		return
-	}
+// The interrupts which are both requested in IF and enabled in IE
+func (cpu *CPU) pendingInterrupts() byte {
+	interruptFlags := cpu.bus.peekByte(IO_IF)
+	ieRegister := cpu.bus.peekByte(INTERRUPT_ENABLE_REGISTER_START)
 
-	if cpu.handleInterrupt(INT_LCD, 0x48) {
-		return
-	}
+	return interruptFlags & ieRegister & 0x1F
+}
 
-	if cpu.handleInterrupt(INT_TIMER, 0x50) {
+// Dispatching an interrupt takes 5 M-cycles: 2 waiting, 2 pushing PC, and 1
+// jumping to the handler. The handler is only picked after the high byte of PC
+// is pushed, so if that push overwrites IE (when SP is 0x0000) and cancels the
+// interrupt, the CPU jumps to 0x0000 instead and IF is left alone.
+// @see https://gbdev.io/pandocs/Interrupts.html#interrupt-handling
+func (cpu *CPU) handleInterrupts() {
+	if cpu.pendingInterrupts() == 0 {
 		return
 	}
 
-	if cpu.handleInterrupt(INT_SERIAL, 0x58) {
-		return
-	}
+	cpu.interruptMasterEnabled = false
+	cpu.halted = false
+	cpu.idle()
+	cpu.idle()
 
-	if cpu.handleInterrupt(INT_JOYPAD, 0x60) {
-		return
-	}
-}
+	hi, lo := Uint16ToBytes(cpu.registers.read(R_PC))
+	sp := cpu.registers.read(R_SP)
 
-func (cpu *CPU) checkInterrupt(kind InterruptKind) bool {
-	interruptFlags := cpu.bus.readByte(IO_IF)
-	ieRegister := cpu.bus.readByte(INTERRUPT_ENABLE_REGISTER_START)
+	sp--
+	cpu.writeByte(sp, hi)
 
-	interruptFlagged := GetBit(interruptFlags, byte(kind))
-	interruptEnabled := GetBit(ieRegister, byte(kind))
+	pending := cpu.pendingInterrupts()
 
-	return interruptFlagged && interruptEnabled
-}
+	sp--
+	cpu.writeByte(sp, lo)
+	cpu.registers.write(R_SP, sp)
 
-func (cpu *CPU) handleInterrupt(kind InterruptKind, address uint16) bool {
-	if !cpu.checkInterrupt(kind) {
-		return false
+	address := uint16(0x0000)
+	for kind := InterruptKind(INT_VBLANK); kind <= INT_JOYPAD; kind++ {
+		if GetBit(pending, byte(kind)) {
+			cpu.gameboy.ClearInterrupt(kind)
+			address = 0x40 + 8*uint16(kind)
+			break
+		}
 	}
 
-	cpu.gameboy.ClearInterrupt(kind)
-	cpu.interruptMasterEnabled = false
-	push(cpu, R_PC)
 	cpu.registers.write(R_PC, address)
-
-	return true
+	cpu.idle()
 }
@@ -0,0 +1,130 @@
+package goboy
+
+import "testing"
+
+const WRAM_TEST_PROGRAM_START = 0xC000
+
+// Runs program from WRAM, with only the timer interrupt enabled if enabled is
+// true, and nothing requested
+func newWramTestGameBoy(t *testing.T, program []byte, enabled bool) *GameBoy {
+	t.Helper()
+
+	gameboy := newTestGameBoy(t)
+	for i, value := range program {
+		gameboy.WriteMemory(WRAM_TEST_PROGRAM_START+uint16(i), value)
+	}
+
+	gameboy.cpu.registers.write(R_PC, WRAM_TEST_PROGRAM_START)
+	gameboy.cpu.registers.write(R_SP, 0xDFF0)
+	gameboy.cpu.registers.write(R_A, 0)
+	gameboy.WriteMemory(IO_IF, 0)
+	gameboy.WriteMemory(INTERRUPT_ENABLE_REGISTER_START, 0)
+	if enabled {
+		gameboy.WriteMemory(INTERRUPT_ENABLE_REGISTER_START, 1<<INT_TIMER)
+	}
+
+	return gameboy
+}
+
+func TestHalt_BugReadsNextByteTwice(t *testing.T) {
+	// HALT; INC A; NOP
+	gameboy := newWramTestGameBoy(t, []byte{0x76, 0x3C, 0x00}, true)
+	gameboy.RequestInterrupt(INT_TIMER)
+
+	gameboy.Step()
+	if gameboy.cpu.halted {
+		t.Fatalf("expected HALT not to halt with IME off and an interrupt pending")
+	}
+
+	gameboy.Step()
+	if pc := gameboy.ReadRegister(R_PC); pc != WRAM_TEST_PROGRAM_START+1 {
+		t.Errorf("expected PC not to be incremented after the halt bug, got 0x%4.4X", pc)
+	}
+
+	gameboy.Step()
+	if a := gameboy.ReadRegister(R_A); a != 2 {
+		t.Errorf("expected INC A to run twice, A is %d", a)
+	}
+	if pc := gameboy.ReadRegister(R_PC); pc != WRAM_TEST_PROGRAM_START+2 {
+		t.Errorf("expected PC to carry on after INC A, got 0x%4.4X", pc)
+	}
+}
+
+func TestHalt_OnlyEnabledInterruptsWake(t *testing.T) {
+	// HALT; INC A
+	gameboy := newWramTestGameBoy(t, []byte{0x76, 0x3C}, false)
+
+	gameboy.Step()
+	if !gameboy.cpu.halted {
+		t.Fatalf("expected HALT to halt with no interrupts pending")
+	}
+
+	gameboy.RequestInterrupt(INT_TIMER)
+	gameboy.Step()
+	if !gameboy.cpu.halted {
+		t.Fatalf("expected a disabled interrupt not to wake the CPU")
+	}
+
+	gameboy.WriteMemory(INTERRUPT_ENABLE_REGISTER_START, 1<<INT_TIMER)
+	gameboy.Step()
+	if gameboy.cpu.halted {
+		t.Fatalf("expected an enabled interrupt to wake the CPU")
+	}
+
+	// With IME off the interrupt isn't handled, the CPU just carries on
+	gameboy.Step()
+	if a := gameboy.ReadRegister(R_A); a != 1 {
+		t.Errorf("expected INC A to run once, A is %d", a)
+	}
+	if pc := gameboy.ReadRegister(R_PC); pc != WRAM_TEST_PROGRAM_START+2 {
+		t.Errorf("expected the interrupt not to be dispatched, PC is 0x%4.4X", pc)
+	}
+}
+
+func TestInterrupts_DispatchTakesFiveCycles(t *testing.T) {
+	// NOP
+	gameboy := newWramTestGameBoy(t, []byte{0x00}, true)
+	gameboy.cpu.interruptMasterEnabled = true
+	gameboy.RequestInterrupt(INT_TIMER)
+
+	// 1 for the NOP and 5 for the dispatch
+	if mCycles := gameboy.Step(); mCycles != 6 {
+		t.Errorf("expected 6 M-cycles, got %d", mCycles)
+	}
+
+	if pc := gameboy.ReadRegister(R_PC); pc != 0x50 {
+		t.Errorf("expected to jump to the timer handler, PC is 0x%4.4X", pc)
+	}
+
+	sp := gameboy.ReadRegister(R_SP)
+	returnAddress := BytesToUint16(gameboy.ReadMemory(sp+1), gameboy.ReadMemory(sp))
+	if sp != 0xDFEE || returnAddress != WRAM_TEST_PROGRAM_START+1 {
+		t.Errorf("expected 0x%4.4X to be pushed, got 0x%4.4X at SP 0x%4.4X", WRAM_TEST_PROGRAM_START+1, returnAddress, sp)
+	}
+
+	if gameboy.ReadMemory(IO_IF)&(1<<INT_TIMER) != 0 {
+		t.Errorf("expected the timer interrupt to be acknowledged")
+	}
+	if gameboy.cpu.interruptMasterEnabled {
+		t.Errorf("expected IME to be switched off")
+	}
+}
+
+func TestInterrupts_IeWriteDuringPushCancelsDispatch(t *testing.T) {
+	// NOP
+	gameboy := newWramTestGameBoy(t, []byte{0x00}, true)
+	gameboy.cpu.interruptMasterEnabled = true
+	gameboy.RequestInterrupt(INT_TIMER)
+
+	// Pushing the high byte of PC (0xC0) writes to IE, disabling the timer
+	gameboy.cpu.registers.write(R_SP, 0x0000)
+	gameboy.Step()
+
+	if pc := gameboy.ReadRegister(R_PC); pc != 0x0000 {
+		t.Errorf("expected a cancelled dispatch to jump to 0x0000, PC is 0x%4.4X", pc)
+	}
+
+	if gameboy.ReadMemory(IO_IF)&(1<<INT_TIMER) == 0 {
+		t.Errorf("expected the timer interrupt to still be requested")
+	}
+}
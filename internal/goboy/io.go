@@ -2,9 +2,13 @@ package goboy
 
 const (
 	IO_IF = 0xFF0F
+	// CGB only speed switch register, which reads as 0xFF on the DMG
+	// @see https://gbdev.io/pandocs/CGB_Registers.html#ff4d--key1-cgb-mode-only-prepare-speed-switch
+	IO_KEY1 = 0xFF4D
 )
 
 type IO struct {
+	bus        *Bus
 	interrupts *InterruptRegister
 	timer      *Timer
 	dma        *DMA
@@ -19,6 +23,7 @@ func NewIO(gameboy *GameBoy, bus *Bus, timer *Timer, interruptEnableRegister *In
 	serial := NewSerial(gameboy)
 
 	return &IO{
+		bus:        bus,
 		interrupts: interruptEnableRegister,
 		timer:      timer,
 		dma:        dma,
@@ -65,6 +70,13 @@ func (io *IO) writeByte(address uint16, value byte) {
 		return
 	}
 
+	if address == IO_BOOT {
+		if value != 0 {
+			io.bus.unmapBootRom()
+		}
+		return
+	}
+
 	// fmt.Printf("Writing to %2.2X not supported (IO_REGISTERS)\n", address)
 }
 
@@ -97,6 +109,16 @@ func (io *IO) readByte(address uint16) byte {
 		return io.lcd.readByte(address)
 	}
 
+	if address == IO_BOOT {
+		return 0xFF
+	}
+
+	// Games (and blargg's tests) read KEY1 to decide whether to switch speed
+	// with STOP, and must see that they're on a DMG
+	if address == IO_KEY1 {
+		return 0xFF
+	}
+
 	// fmt.Printf("Reading from %2.2X not supported (IO_REGISTERS)\n", address)
 	return 0
 }
@@ -77,3 +77,13 @@ func (joypad *Joypad) Release(button Button) {
 func (joypad *Joypad) Check(button Button) bool {
 	return GetBit(joypad.buttons, byte(button))
 }
+
+// The buttons currently held down come from the host rather than the game, so
+// only the selection bits written by the game are part of the state
+func (joypad *Joypad) saveState(s *stateWriter) {
+	s.write(joypad.data)
+}
+
+func (joypad *Joypad) loadState(s *stateReader) {
+	s.read(&joypad.data)
+}
@@ -267,3 +267,19 @@ func (lcd *LCD) IncrementLy() {
 		lcd.SetLcdStatusFlag(STAT_LYC_EQUAL, false)
 	}
 }
+
+func (lcd *LCD) saveState(s *stateWriter) {
+	s.write(lcd.lcdc, lcd.stat, lcd.ly, lcd.lyc, lcd.scy, lcd.scx, lcd.wx, lcd.wy)
+	s.write(lcd.bgp, lcd.obj0, lcd.obj1)
+}
+
+func (lcd *LCD) loadState(s *stateReader) {
+	s.read(&lcd.lcdc, &lcd.stat, &lcd.ly, &lcd.lyc, &lcd.scy, &lcd.scx, &lcd.wx, &lcd.wy)
+	s.read(&lcd.bgp, &lcd.obj0, &lcd.obj1)
+
+	// The colors are derived from the palette registers, so they don't need to
+	// be stored separately
+	lcd.updatePalette(&lcd.bgColors, lcd.bgp)
+	lcd.updatePalette(&lcd.sp1Colors, lcd.obj0&0b11111100)
+	lcd.updatePalette(&lcd.sp2Colors, lcd.obj1&0b11111100)
+}
@@ -0,0 +1,56 @@
+package goboy
+
+import "fmt"
+
+// Running one of the unused opcodes locks up the CPU until the Game Boy is
+// reset. Everything else keeps running, so the screen and sound carry on, but
+// no more instructions run and interrupts are ignored.
+// @see https://gist.github.com/SonoSooS/c0055300670d678b5ae8433e20bea595#opcode-holes-not-implemented-opcodes
+type LockupFault struct {
+	PC     uint16
+	Opcode byte
+	// The ROM bank mapped at PC, or -1 if the CPU was running from RAM
+	Bank int
+	// The closest label before PC, e.g. "main+3", if the ROM has symbols
+	Symbol string
+}
+
+func (fault LockupFault) Error() string {
+	location := fmt.Sprintf("0x%4.4X", fault.PC)
+	if fault.Bank >= 0 {
+		location = fmt.Sprintf("%2.2X:%4.4X", fault.Bank, fault.PC)
+	}
+	if fault.Symbol != "" {
+		location += fmt.Sprintf(" (%s)", fault.Symbol)
+	}
+
+	return fmt.Sprintf("CPU locked up running invalid opcode 0x%2.2X at %s", fault.Opcode, location)
+}
+
+// Called when the CPU locks up, with where it happened
+type LockupCallback func(fault LockupFault)
+
+// Takes the opcode and its address as the CPU fetched them, rather than
+// working them out again from PC and memory
+func (cpu *CPU) lockUp(pc uint16, opcode byte) {
+	bank := cpu.gameboy.cartridge.romBankAt(pc)
+	fault := LockupFault{
+		PC:     pc,
+		Opcode: opcode,
+		Bank:   bank,
+		Symbol: cpu.gameboy.symbols.Nearest(bank, pc),
+	}
+
+	cpu.lockedUp = true
+
+	// The instructions leading up to the lockup are the interesting ones
+	if tracer := cpu.gameboy.tracer; tracer != nil {
+		if err := tracer.Dump(); err != nil {
+			fmt.Println(err)
+		}
+	}
+
+	for _, callback := range cpu.gameboy.lockupCallbacks {
+		callback(fault)
+	}
+}
@@ -0,0 +1,119 @@
+package goboy
+
+import (
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestLockup_StopsTheCpuButNotTheMachine(t *testing.T) {
+	// An invalid opcode, then INC A
+	gameboy := newWramTestGameBoy(t, []byte{0xD3, 0x3C}, true)
+
+	var faults []LockupFault
+	gameboy.RegisterLockupCallback(func(fault LockupFault) {
+		faults = append(faults, fault)
+	})
+
+	gameboy.Step()
+
+	expected := LockupFault{PC: WRAM_TEST_PROGRAM_START, Opcode: 0xD3, Bank: -1}
+	if len(faults) != 1 || faults[0] != expected {
+		t.Fatalf("expected a single fault %+v, got %+v", expected, faults)
+	}
+	if !gameboy.LockedUp() {
+		t.Fatalf("expected the CPU to be locked up")
+	}
+
+	// Interrupts don't get it going again either
+	gameboy.cpu.interruptMasterEnabled = true
+	gameboy.RequestInterrupt(INT_TIMER)
+
+	for range 100 {
+		if mCycles := gameboy.Step(); mCycles != 1 {
+			t.Fatalf("expected the rest of the machine to keep running, took %d M-cycles", mCycles)
+		}
+	}
+
+	if pc := gameboy.ReadRegister(R_PC); pc != WRAM_TEST_PROGRAM_START+1 {
+		t.Errorf("expected PC to stay put, got 0x%4.4X", pc)
+	}
+	if a := gameboy.ReadRegister(R_A); a != 0 {
+		t.Errorf("expected nothing else to run, A is %d", a)
+	}
+	if len(faults) != 1 {
+		t.Errorf("expected the fault to be reported once, got %d", len(faults))
+	}
+
+	gameboy.Reset()
+	if gameboy.LockedUp() {
+		t.Errorf("expected a reset to unlock the CPU")
+	}
+}
+
+func TestLockup_AfterHaltBug(t *testing.T) {
+	// HALT, then an invalid opcode which the halt bug fetches without moving PC
+	gameboy := newWramTestGameBoy(t, []byte{0x76, 0xD3}, true)
+	gameboy.RequestInterrupt(INT_TIMER)
+
+	var faults []LockupFault
+	gameboy.RegisterLockupCallback(func(fault LockupFault) {
+		faults = append(faults, fault)
+	})
+
+	gameboy.Step()
+	gameboy.Step()
+
+	expected := LockupFault{PC: WRAM_TEST_PROGRAM_START + 1, Opcode: 0xD3, Bank: -1}
+	if len(faults) != 1 || faults[0] != expected {
+		t.Errorf("expected a single fault %+v, got %+v", expected, faults)
+	}
+}
+
+func TestLockup_ReportsRomBank(t *testing.T) {
+	// MBC1 with 4 banks
+	path := writeTestRom(t, 0x01, 0x01, 0x00)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// LD A, 3; LD (0x2000), A; JP 0x4000
+	copy(data[0x0100:], []byte{0x3E, 0x03, 0xEA, 0x00, 0x20, 0xC3, 0x00, 0x40})
+	data[3*0x4000] = 0xDB
+
+	options := DefaultOptions()
+	options.Headless = true
+	gameboy, err := NewGameBoyFromRom(options, data, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var fault LockupFault
+	gameboy.RegisterLockupCallback(func(f LockupFault) {
+		fault = f
+	})
+
+	for range 4 {
+		gameboy.Step()
+	}
+
+	expected := LockupFault{PC: 0x4000, Opcode: 0xDB, Bank: 3}
+	if fault != expected {
+		t.Errorf("expected %+v, got %+v", expected, fault)
+	}
+}
+
+func TestRunTestRom_Lockup(t *testing.T) {
+	path := writeTestRomWithCode(t, []byte{0xE3})
+
+	report := RunTestRom(path, 10)
+	if report.Result != TEST_ROM_ERROR {
+		t.Errorf("expected a lockup to be an error, got %v", report.Result)
+	}
+
+	var fault LockupFault
+	if !errors.As(report.Err, &fault) || fault.PC != 0x0100 {
+		t.Errorf("expected the lockup fault, got %v", report.Err)
+	}
+}
@@ -0,0 +1,90 @@
+package goboy
+
+import "fmt"
+
+// A memory bank controller sits between the bus and the cartridge's ROM and
+// RAM chips, deciding which banks are visible in the cartridge address ranges
+// @see https://gbdev.io/pandocs/MBCs.html
+type MBC interface {
+	readByte(address uint16) byte
+	writeByte(address uint16, value byte)
+	// The ROM bank currently visible at an address between 0x0000 and 0x7FFF,
+	// before wrapping to the size of the ROM
+	mappedRomBank(address uint16) int
+	// The RAM bank currently visible between 0xA000 and 0xBFFF, before wrapping
+	// to the amount of RAM, or -1 if something other than RAM is mapped there
+	mappedRamBank() int
+	saveState(s *stateWriter)
+	loadState(s *stateReader)
+}
+
+type UnsupportedCartridgeError struct {
+	CartridgeType byte
+}
+
+func (err UnsupportedCartridgeError) Error() string {
+	return fmt.Sprintf(
+		"unsupported cartridge type %s (0x%2.2X)",
+		mapToFriendlyName(CARTRIDGE_TYPE_MAP, err.CartridgeType),
+		err.CartridgeType,
+	)
+}
+
+func NewMBC(cartridge *Cartridge) (MBC, error) {
+	switch cartridge.header.cartridgeType {
+	case 0x00, 0x08, 0x09:
+		return NewRomOnly(cartridge), nil
+	case 0x01, 0x02, 0x03:
+		return NewMBC1(cartridge), nil
+	case 0x05, 0x06:
+		return NewMBC2(cartridge), nil
+	case 0x0F, 0x10, 0x11, 0x12, 0x13:
+		return NewMBC3(cartridge), nil
+	case 0x19, 0x1A, 0x1B, 0x1C, 0x1D, 0x1E:
+		return NewMBC5(cartridge), nil
+	default:
+		return nil, UnsupportedCartridgeError{CartridgeType: cartridge.header.cartridgeType}
+	}
+}
+
+// Cartridges without a controller map the first 32 KiB of ROM directly, and
+// optionally a single bank of RAM
+type RomOnly struct {
+	cartridge *Cartridge
+}
+
+func NewRomOnly(cartridge *Cartridge) *RomOnly {
+	return &RomOnly{cartridge: cartridge}
+}
+
+func (mbc *RomOnly) readByte(address uint16) byte {
+	switch {
+	case address <= SWITCHABLE_ROM_BANK_END:
+		return mbc.cartridge.readRom(mbc.mappedRomBank(address), address)
+
+	case Between(address, EXTERNAL_RAM_START, EXTERNAL_RAM_END):
+		return mbc.cartridge.readRam(0, address)
+
+	default:
+		panic(fmt.Sprintf("Failed to read from cartridge at 0x%4.4X", address))
+	}
+}
+
+func (mbc *RomOnly) mappedRomBank(address uint16) int {
+	return int(address / 0x4000)
+}
+
+func (mbc *RomOnly) mappedRamBank() int {
+	return 0
+}
+
+func (mbc *RomOnly) writeByte(address uint16, value byte) {
+	// There's nothing listening for writes to ROM, so they're dropped
+	if Between(address, EXTERNAL_RAM_START, EXTERNAL_RAM_END) {
+		mbc.cartridge.writeRam(0, address, value)
+	}
+}
+
+func (mbc *RomOnly) saveState(s *stateWriter) {}
+
+func (mbc *RomOnly) loadState(s *stateReader) {}
@@ -0,0 +1,135 @@
+package goboy
+
+import "fmt"
+
+// @see https://gbdev.io/pandocs/MBC1.html
+type MBC1 struct {
+	cartridge  *Cartridge
+	ramEnabled bool
+
+	// 5 bit register selecting the switchable ROM bank
+	romBank byte
+	// 2 bit register selecting a RAM bank, or the upper bits of the ROM bank
+	upperBank byte
+	// In mode 1 the upper bank register also applies to the 0x0000-0x3FFF ROM
+	// area and to RAM
+	bankingMode byte
+
+	// MBC1M multicarts wire the upper bank register one bit lower, so that each
+	// game on the cartridge sees its own 256 KiB
+	multicart bool
+}
+
+func NewMBC1(cartridge *Cartridge) *MBC1 {
+	return &MBC1{
+		cartridge: cartridge,
+		romBank:   1,
+		multicart: isMBC1Multicart(cartridge.romData),
+	}
+}
+
+// There's nothing in the header to tell a multicart apart from a normal 1 MiB
+// MBC1 cartridge, but each game on a multicart has its own copy of the Nintendo
+// logo at the start of its 256 KiB
+// @see https://gbdev.io/pandocs/MBC1.html#mbc1m-1-mib-multi-game-compilation-carts
+func isMBC1Multicart(romData []byte) bool {
+	if len(romData) != 1024*1024 {
+		return false
+	}
+
+	logo := romData[0x0104:0x0134]
+	bankStart := 0x10 * 0x4000
+
+	for i := range logo {
+		if romData[bankStart+0x0104+i] != logo[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (mbc *MBC1) upperBankShift() byte {
+	if mbc.multicart {
+		return 4
+	}
+
+	return 5
+}
+
+func (mbc *MBC1) readByte(address uint16) byte {
+	switch {
+	case address <= SWITCHABLE_ROM_BANK_END:
+		return mbc.cartridge.readRom(mbc.mappedRomBank(address), address)
+
+	case Between(address, EXTERNAL_RAM_START, EXTERNAL_RAM_END):
+		if !mbc.ramEnabled {
+			return 0xFF
+		}
+		return mbc.cartridge.readRam(mbc.mappedRamBank(), address)
+
+	default:
+		panic(fmt.Sprintf("Failed to read from cartridge at 0x%4.4X", address))
+	}
+}
+
+func (mbc *MBC1) writeByte(address uint16, value byte) {
+	switch {
+	case Between(address, 0x0000, 0x1FFF):
+		mbc.ramEnabled = (value & 0x0F) == 0x0A
+
+	case Between(address, 0x2000, 0x3FFF):
+		// Bank 0 can't be selected here, but the check only looks at the 5 bits of
+		// the register so banks 0x20, 0x40 and 0x60 get skipped too
+		bank := value & 0x1F
+		if bank == 0 {
+			bank = 1
+		}
+		mbc.romBank = bank
+
+	case Between(address, 0x4000, 0x5FFF):
+		mbc.upperBank = value & 0x03
+
+	case Between(address, 0x6000, 0x7FFF):
+		mbc.bankingMode = value & 0x01
+
+	case Between(address, EXTERNAL_RAM_START, EXTERNAL_RAM_END):
+		if mbc.ramEnabled {
+			mbc.cartridge.writeRam(mbc.mappedRamBank(), address, value)
+		}
+
+	default:
+		panic(fmt.Sprintf("Can't write to cartridge at 0x%4.4X=0x%2.2X", address, value))
+	}
+}
+
+func (mbc *MBC1) mappedRomBank(address uint16) int {
+	if address <= ROM_BANK_0_END {
+		if mbc.bankingMode == 1 {
+			return int(mbc.upperBank) << mbc.upperBankShift()
+		}
+		return 0
+	}
+
+	lowerBank := mbc.romBank
+	if mbc.multicart {
+		lowerBank &= 0x0F
+	}
+	return int(mbc.upperBank)<<mbc.upperBankShift() | int(lowerBank)
+}
+
+func (mbc *MBC1) mappedRamBank() int {
+	if mbc.bankingMode == 1 {
+		return int(mbc.upperBank)
+	}
+
+	return 0
+}
+
+func (mbc *MBC1) saveState(s *stateWriter) {
+	s.write(mbc.ramEnabled, mbc.romBank, mbc.upperBank, mbc.bankingMode)
+}
+
+func (mbc *MBC1) loadState(s *stateReader) {
+	s.read(&mbc.ramEnabled, &mbc.romBank, &mbc.upperBank, &mbc.bankingMode)
+}
@@ -0,0 +1,94 @@
+package goboy
+
+import "fmt"
+
+var MBC2_CARTRIDGE_TYPES = map[byte]bool{
+	0x05: true, // MBC2
+	0x06: true, // MBC2+BATTERY
+}
+
+// MBC2 has 512 half-byte RAM cells built in to the controller
+const MBC2_RAM_SIZE = 0x200
+
+// @see https://gbdev.io/pandocs/MBC2.html
+type MBC2 struct {
+	cartridge  *Cartridge
+	romBank    byte
+	ramEnabled bool
+}
+
+func NewMBC2(cartridge *Cartridge) *MBC2 {
+	return &MBC2{
+		cartridge: cartridge,
+		romBank:   1,
+	}
+}
+
+func (mbc *MBC2) readByte(address uint16) byte {
+	switch {
+	case address <= SWITCHABLE_ROM_BANK_END:
+		return mbc.cartridge.readRom(mbc.mappedRomBank(address), address)
+
+	case Between(address, EXTERNAL_RAM_START, EXTERNAL_RAM_END):
+		if !mbc.ramEnabled {
+			return 0xFF
+		}
+		// Only the lower nibble is connected, the upper one floats high
+		return mbc.cartridge.readRam(0, mirrorMBC2Ram(address)) | 0xF0
+
+	default:
+		panic(fmt.Sprintf("Failed to read from cartridge at 0x%4.4X", address))
+	}
+}
+
+func (mbc *MBC2) mappedRomBank(address uint16) int {
+	if address <= ROM_BANK_0_END {
+		return 0
+	}
+	return int(mbc.romBank)
+}
+
+func (mbc *MBC2) mappedRamBank() int {
+	return 0
+}
+
+func (mbc *MBC2) writeByte(address uint16, value byte) {
+	switch {
+	case address <= ROM_BANK_0_END:
+		// Both registers live in the same range, bit 8 of the address picks
+		// which one is written
+		if GetBit(byte(address>>8), 0) {
+			bank := value & 0x0F
+			if bank == 0 {
+				bank = 1
+			}
+			mbc.romBank = bank
+		} else {
+			mbc.ramEnabled = (value & 0x0F) == 0x0A
+		}
+
+	case address <= SWITCHABLE_ROM_BANK_END:
+		// Nothing is mapped here on MBC2
+
+	case Between(address, EXTERNAL_RAM_START, EXTERNAL_RAM_END):
+		if mbc.ramEnabled {
+			mbc.cartridge.writeRam(0, mirrorMBC2Ram(address), value&0x0F)
+		}
+
+	default:
+		panic(fmt.Sprintf("Can't write to cartridge at 0x%4.4X=0x%2.2X", address, value))
+	}
+}
+
+// The 512 cells repeat across the whole external RAM range
+func mirrorMBC2Ram(address uint16) uint16 {
+	return EXTERNAL_RAM_START + (address-EXTERNAL_RAM_START)%MBC2_RAM_SIZE
+}
+
+func (mbc *MBC2) saveState(s *stateWriter) {
+	s.write(mbc.romBank, mbc.ramEnabled)
+}
+
+func (mbc *MBC2) loadState(s *stateReader) {
+	s.read(&mbc.romBank, &mbc.ramEnabled)
+}
@@ -0,0 +1,128 @@
+package goboy
+
+import "fmt"
+
+// @see https://gbdev.io/pandocs/MBC3.html
+type MBC3 struct {
+	cartridge *Cartridge
+	romBank   byte
+	// Either a RAM bank (0x00-0x03) or one of the clock registers (0x08-0x0C)
+	ramBank    byte
+	ramEnabled bool
+}
+
+func NewMBC3(cartridge *Cartridge) *MBC3 {
+	return &MBC3{
+		cartridge: cartridge,
+		romBank:   1,
+	}
+}
+
+func (mbc *MBC3) readByte(address uint16) byte {
+	switch {
+	case address <= SWITCHABLE_ROM_BANK_END:
+		return mbc.cartridge.readRom(mbc.mappedRomBank(address), address)
+
+	case Between(address, EXTERNAL_RAM_START, EXTERNAL_RAM_END):
+		if !mbc.ramEnabled {
+			return 0xFF
+		}
+
+		if mbc.ramBank >= RTC_S {
+			return mbc.readRTCRegister(mbc.ramBank)
+		}
+
+		return mbc.cartridge.readRam(int(mbc.ramBank), address)
+
+	default:
+		panic(fmt.Sprintf("Failed to read from cartridge at 0x%4.4X", address))
+	}
+}
+
+func (mbc *MBC3) mappedRomBank(address uint16) int {
+	if address <= ROM_BANK_0_END {
+		return 0
+	}
+	return int(mbc.romBank)
+}
+
+// The clock registers are mapped in place of RAM banks 0x08 to 0x0C
+func (mbc *MBC3) mappedRamBank() int {
+	if mbc.ramBank >= RTC_S {
+		return -1
+	}
+	return int(mbc.ramBank)
+}
+
+func (mbc *MBC3) writeByte(address uint16, value byte) {
+	switch {
+	case Between(address, 0x0000, 0x1FFF):
+		mbc.ramEnabled = (value & 0x0F) == 0x0A
+
+	case Between(address, 0x2000, 0x3FFF):
+		bank := value & 0x7F
+		if bank == 0 {
+			bank = 1
+		}
+		mbc.romBank = bank
+
+	case Between(address, 0x4000, 0x5FFF):
+		if value <= 0x03 {
+			mbc.ramBank = value
+		} else if value >= RTC_S && value <= RTC_DH {
+			mbc.ramBank = value
+		}
+
+	case Between(address, 0x6000, 0x7FFF):
+		mbc.latchClockData(value)
+
+	case Between(address, EXTERNAL_RAM_START, EXTERNAL_RAM_END):
+		if !mbc.ramEnabled {
+			return
+		}
+
+		if mbc.ramBank >= RTC_S {
+			mbc.writeRTCRegister(mbc.ramBank, value)
+		} else {
+			mbc.cartridge.writeRam(int(mbc.ramBank), address, value)
+		}
+
+	default:
+		panic(fmt.Sprintf("Can't write to cartridge at 0x%4.4X=0x%2.2X", address, value))
+	}
+}
+
+func (mbc *MBC3) readRTCRegister(index byte) byte {
+	if mbc.cartridge.rtc == nil {
+		return 0xFF
+	}
+
+	return mbc.cartridge.rtc.readRegister(index)
+}
+
+func (mbc *MBC3) writeRTCRegister(index byte, value byte) {
+	if mbc.cartridge.rtc == nil {
+		return
+	}
+
+	mbc.cartridge.rtc.writeRegister(index, value)
+	mbc.cartridge.markRamDirty()
+}
+
+func (mbc *MBC3) latchClockData(value byte) {
+	if mbc.cartridge.rtc == nil {
+		return
+	}
+
+	mbc.cartridge.rtc.latch(value)
+}
+
+// This matches the layout states used before cartridges had their own MBC, so
+// older MBC3 states still load
+func (mbc *MBC3) saveState(s *stateWriter) {
+	s.write(mbc.romBank, mbc.ramBank, mbc.ramEnabled)
+}
+
+func (mbc *MBC3) loadState(s *stateReader) {
+	s.read(&mbc.romBank, &mbc.ramBank, &mbc.ramEnabled)
+}
@@ -0,0 +1,118 @@
+package goboy
+
+import "fmt"
+
+var RUMBLE_CARTRIDGE_TYPES = map[byte]bool{
+	0x1C: true, // MBC5+RUMBLE
+	0x1D: true, // MBC5+RUMBLE+RAM
+	0x1E: true, // MBC5+RUMBLE+RAM+BATTERY
+}
+
+// Called whenever a rumble cartridge switches its motor on or off
+type RumbleCallback func(on bool)
+
+// @see https://gbdev.io/pandocs/MBC5.html
+type MBC5 struct {
+	cartridge *Cartridge
+	// 9 bit register, split across two addresses. Unlike the older controllers
+	// bank 0 can be mapped into the switchable area
+	romBank    uint16
+	ramBank    byte
+	ramEnabled bool
+
+	// Rumble carts use bit 3 of the RAM bank register to drive the motor, so only
+	// have 8 RAM banks available
+	hasRumble bool
+	rumbling  bool
+}
+
+func NewMBC5(cartridge *Cartridge) *MBC5 {
+	return &MBC5{
+		cartridge: cartridge,
+		romBank:   1,
+		hasRumble: RUMBLE_CARTRIDGE_TYPES[cartridge.header.cartridgeType],
+	}
+}
+
+func (mbc *MBC5) readByte(address uint16) byte {
+	switch {
+	case address <= SWITCHABLE_ROM_BANK_END:
+		return mbc.cartridge.readRom(mbc.mappedRomBank(address), address)
+
+	case Between(address, EXTERNAL_RAM_START, EXTERNAL_RAM_END):
+		if !mbc.ramEnabled {
+			return 0xFF
+		}
+		return mbc.cartridge.readRam(int(mbc.ramBank), address)
+
+	default:
+		panic(fmt.Sprintf("Failed to read from cartridge at 0x%4.4X", address))
+	}
+}
+
+func (mbc *MBC5) mappedRomBank(address uint16) int {
+	if address <= ROM_BANK_0_END {
+		return 0
+	}
+	return int(mbc.romBank)
+}
+
+func (mbc *MBC5) mappedRamBank() int {
+	return int(mbc.ramBank)
+}
+
+func (mbc *MBC5) writeByte(address uint16, value byte) {
+	switch {
+	case Between(address, 0x0000, 0x1FFF):
+		mbc.ramEnabled = (value & 0x0F) == 0x0A
+
+	case Between(address, 0x2000, 0x2FFF):
+		mbc.romBank = (mbc.romBank & 0x100) | uint16(value)
+
+	case Between(address, 0x3000, 0x3FFF):
+		mbc.romBank = (mbc.romBank & 0xFF) | uint16(value&0x01)<<8
+
+	case Between(address, 0x4000, 0x5FFF):
+		if mbc.hasRumble {
+			mbc.ramBank = value & 0x07
+			mbc.setRumbling(GetBit(value, 3))
+		} else {
+			mbc.ramBank = value & 0x0F
+		}
+
+	case Between(address, 0x6000, 0x7FFF):
+		// Nothing is mapped here on MBC5
+
+	case Between(address, EXTERNAL_RAM_START, EXTERNAL_RAM_END):
+		if mbc.ramEnabled {
+			mbc.cartridge.writeRam(int(mbc.ramBank), address, value)
+		}
+
+	default:
+		panic(fmt.Sprintf("Can't write to cartridge at 0x%4.4X=0x%2.2X", address, value))
+	}
+}
+
+func (mbc *MBC5) setRumbling(on bool) {
+	if on == mbc.rumbling {
+		return
+	}
+
+	mbc.rumbling = on
+	for _, callback := range mbc.cartridge.rumbleCallbacks {
+		callback(on)
+	}
+}
+
+func (mbc *MBC5) saveState(s *stateWriter) {
+	s.write(mbc.romBank, mbc.ramBank, mbc.ramEnabled, mbc.rumbling)
+}
+
+func (mbc *MBC5) loadState(s *stateReader) {
+	var rumbling bool
+	s.read(&mbc.romBank, &mbc.ramBank, &mbc.ramEnabled, &rumbling)
+
+	if s.err == nil {
+		mbc.setRumbling(rumbling)
+	}
+}
@@ -0,0 +1,56 @@
+package goboy
+
+// Options control how a GameBoy is created and how the emulator is presented
+// to the user. The zero value is not useful on its own, use DefaultOptions and
+// then override whatever needs changing.
+type Options struct {
+	// Path to the ROM file to load into the cartridge slot
+	RomPath string
+	// Optional path to a 256 byte DMG boot ROM
+	BootRomPath string
+	// Directory used for battery saves. If empty, saves are kept next to the ROM
+	SaveDir string
+	// How many screen pixels are used for each Game Boy pixel
+	Scale int
+	// Run without opening any windows or audio devices
+	Headless bool
+	// Emulation speed as a multiple of real hardware. 0 runs unthrottled
+	Speed float64
+	// Whether to open the tile debug window alongside the LCD
+	DebugWindows bool
+	// If set, bytes sent over the serial port are appended to this file
+	SerialOutPath string
+	// If set, a trace of every instruction the CPU runs is written to this file
+	TracePath string
+	// What goes in the trace, if there is one
+	Trace TraceOptions
+	// RGBDS symbol file for the ROM. If empty, the .sym file next to the ROM is
+	// used if there is one
+	SymbolPath string
+	// Cheat file for the ROM. If empty, the .cht file next to the ROM is used if
+	// there is one
+	CheatPath string
+	// Extra Game Genie or GameShark codes to turn on, on top of the cheat file
+	Cheats []string
+	// Print the cartridge header when loading it, and frame rates once a second
+	Verbose bool
+}
+
+func DefaultOptions() Options {
+	return Options{
+		Scale:        2,
+		Speed:        1,
+		DebugWindows: false,
+		Trace:        DefaultTraceOptions(),
+	}
+}
+
+// The PPU limits itself to a fixed number of frames per second, which we can
+// scale to speed up or slow down emulation
+func (options Options) fpsLimit() int {
+	if options.Speed <= 0 {
+		return 0
+	}
+
+	return max(int(60*options.Speed), 1)
+}
@@ -72,9 +72,9 @@ func (pf *PixelFifo) SetState(state FetchState) {
 }
 
 func (pf *PixelFifo) Process() {
-	pf.mapX = (pf.fetchX + pf.bus.readByte(LCD_SCX)) / 8
-	pf.mapY = (pf.bus.readByte(LCD_LY) + pf.bus.readByte(LCD_SCY)) / 8
-	pf.tileY = ((pf.bus.readByte(LCD_LY) + pf.bus.readByte(LCD_SCY)) % 8) * 2
+	pf.mapX = (pf.fetchX + pf.bus.peekByte(LCD_SCX)) / 8
+	pf.mapY = (pf.bus.peekByte(LCD_LY) + pf.bus.peekByte(LCD_SCY)) / 8
+	pf.tileY = ((pf.bus.peekByte(LCD_LY) + pf.bus.peekByte(LCD_SCY)) % 8) * 2
 
 	if pf.ppu.scanlineTicks%2 == 0 {
 		pf.Fetch()
@@ -87,8 +87,8 @@ func (pf *PixelFifo) Push() {
 	if len(pf.data) > 8 {
 		data := pf.pop()
 
-		if pf.lineX >= pf.bus.readByte(LCD_SCX)%8 {
-			index := uint32(pf.pushedX) + (uint32(pf.bus.readByte(LCD_LY)) * LCD_WIDTH)
+		if pf.lineX >= pf.bus.peekByte(LCD_SCX)%8 {
+			index := uint32(pf.pushedX) + (uint32(pf.bus.peekByte(LCD_LY)) * LCD_WIDTH)
 			pf.ppu.videoBuffer[index] = data
 			pf.pushedX += 1
 		}
@@ -105,7 +105,7 @@ func (pf *PixelFifo) Fetch() {
 		pf.fetchedOamEntries = nil
 
 		if lcd.IsBgwEnabled() {
-			pf.bgwFetchData[0] = pf.bus.readByte(lcd.BgTileMapOffset() + uint16(pf.mapX) + (uint16(pf.mapY) * 32))
+			pf.bgwFetchData[0] = pf.bus.peekByte(lcd.BgTileMapOffset() + uint16(pf.mapX) + (uint16(pf.mapY) * 32))
 
 			if lcd.BgwTileDataOffset() == 0x8800 {
 				pf.bgwFetchData[0] += 128
@@ -122,12 +122,12 @@ func (pf *PixelFifo) Fetch() {
 		pf.fetchState = FETCH_STATE_DATA_LO
 	case FETCH_STATE_DATA_LO:
 		address := lcd.BgwTileDataOffset() + uint16(pf.bgwFetchData[0])*16 + uint16(pf.tileY)
-		pf.bgwFetchData[1] = pf.bus.readByte(address)
+		pf.bgwFetchData[1] = pf.bus.peekByte(address)
 		pf.loadSpriteData(0)
 		pf.fetchState = FETCH_STATE_DATA_HI
 	case FETCH_STATE_DATA_HI:
 		address := lcd.BgwTileDataOffset() + uint16(pf.bgwFetchData[0])*16 + uint16(pf.tileY) + 1
-		pf.bgwFetchData[2] = pf.bus.readByte(address)
+		pf.bgwFetchData[2] = pf.bus.peekByte(address)
 		pf.loadSpriteData(1)
 		pf.fetchState = FETCH_STATE_SLEEP
 	case FETCH_STATE_SLEEP:
@@ -144,7 +144,7 @@ func (pf *PixelFifo) add() bool {
 		return false
 	}
 
-	x := pf.fetchX - (pf.bus.readByte(LCD_SCX) % 8)
+	x := pf.fetchX - (pf.bus.peekByte(LCD_SCX) % 8)
 	for i := 0; i < 8; i++ {
 		bit := 7 - i
 
@@ -172,7 +172,7 @@ func (pf *PixelFifo) add() bool {
 }
 
 func (pf *PixelFifo) loadSpriteData(offset int) {
-	ly := pf.bus.readByte(LCD_LY)
+	ly := pf.bus.peekByte(LCD_LY)
 	spriteHeight := pf.lcd.ObjSize()
 
 	for i := 0; i < len(pf.fetchedOamEntries); i++ {
@@ -189,14 +189,14 @@ func (pf *PixelFifo) loadSpriteData(offset int) {
 		}
 
 		address := VIDEO_RAM_START + uint16(tileIndex)*16 + uint16(tileY) + uint16(offset)
-		pf.oamFetchData[(i*2)+offset] = pf.bus.readByte(address)
+		pf.oamFetchData[(i*2)+offset] = pf.bus.peekByte(address)
 	}
 }
 
 func (pf *PixelFifo) loadSpriteTile() {
 	for i := 0; i < len(pf.ppu.lineSprites); i++ {
 		sprite := pf.ppu.lineSprites[i]
-		spriteX := sprite.x - 8 + pf.bus.readByte(LCD_SCX)%8
+		spriteX := sprite.x - 8 + pf.bus.peekByte(LCD_SCX)%8
 
 		if (spriteX >= pf.fetchX && spriteX < pf.fetchX+8) ||
 			((spriteX+8) >= pf.fetchX && (spriteX) < pf.fetchX) {
@@ -214,9 +214,9 @@ func (pf *PixelFifo) loadWindowTile() {
 		return
 	}
 
-	wx := pf.bus.readByte(LCD_WX)
-	wy := pf.bus.readByte(LCD_WY)
-	ly := pf.bus.readByte(LCD_LY)
+	wx := pf.bus.peekByte(LCD_WX)
+	wy := pf.bus.peekByte(LCD_WY)
+	ly := pf.bus.peekByte(LCD_LY)
 
 	fetchX := pf.fetchX + 7
 
@@ -232,7 +232,7 @@ func (pf *PixelFifo) loadWindowTile() {
 			base := pf.lcd.WindowTileMapOffset()
 			address := base + uint16(tx) + uint16(ty)*32
 
-			pf.bgwFetchData[0] = pf.bus.readByte(address)
+			pf.bgwFetchData[0] = pf.bus.peekByte(address)
 			if pf.lcd.BgwTileDataOffset() == 0x8800 {
 				pf.bgwFetchData[0] += 128
 			}
@@ -245,7 +245,7 @@ func (pf *PixelFifo) fetchSpritePixels(bgColor uint32, bgColorIndex byte) uint32
 
 	for i := 0; i < len(pf.fetchedOamEntries); i++ {
 		sprite := pf.fetchedOamEntries[i]
-		spriteX := sprite.x - 8 + pf.bus.readByte(LCD_SCX)%8
+		spriteX := sprite.x - 8 + pf.bus.peekByte(LCD_SCX)%8
 
 		if spriteX+8 < pf.fifoX {
 			continue
@@ -291,3 +291,28 @@ func (pf *PixelFifo) fetchSpritePixels(bgColor uint32, bgColorIndex byte) uint32
 func (pf *PixelFifo) Reset() {
 	pf.data = nil
 }
+
+func (pf *PixelFifo) saveState(s *stateWriter) {
+	s.write(uint16(len(pf.data)), pf.data)
+	s.write(pf.fetchState, pf.lineX, pf.pushedX, pf.fetchX)
+	s.write(pf.bgwFetchData, pf.oamFetchData)
+	s.writeOamEntries(pf.fetchedOamEntries)
+	s.write(pf.mapX, pf.mapY, pf.tileY, pf.fifoX)
+}
+
+func (pf *PixelFifo) loadState(s *stateReader) {
+	var count uint16
+	s.read(&count)
+
+	if s.err == nil && count > 16 {
+		s.fail(ErrNotASaveState)
+		return
+	}
+
+	pf.data = make([]uint32, count)
+	s.read(pf.data)
+	s.read(&pf.fetchState, &pf.lineX, &pf.pushedX, &pf.fetchX)
+	s.read(&pf.bgwFetchData, &pf.oamFetchData)
+	pf.fetchedOamEntries = s.readOamEntries()
+	s.read(&pf.mapX, &pf.mapY, &pf.tileY, &pf.fifoX)
+}
@@ -54,14 +54,14 @@ type PPU struct {
 	frameCount        int64
 }
 
-func NewPPU(gameboy *GameBoy, bus *Bus, lcd *LCD) *PPU {
+func NewPPU(gameboy *GameBoy, bus *Bus, lcd *LCD, fpsLimit int, verbose bool) *PPU {
 	ppu := &PPU{
 		gameboy:  gameboy,
 		bus:      bus,
 		lcd:      lcd,
 		vram:     NewRAM(8192, VIDEO_RAM_START),
 		oam:      &[40]OamEntry{},
-		fpsTimer: NewFPSTimer("fps", 60),
+		fpsTimer: NewFPSTimer("fps", fpsLimit, verbose),
 
 		// sprites
 		lineSprites: make([]OamEntry, 40),
@@ -153,7 +153,7 @@ func (ppu *PPU) loadLineSprites() {
 	ppu.lineSprites = nil
 
 	// This is the line we're fetching sprites for
-	ly := ppu.bus.readByte(LCD_LY)
+	ly := ppu.bus.peekByte(LCD_LY)
 	spriteHeight := ppu.lcd.ObjSize()
 
 	for i := 0; i < len(ppu.oam); i++ {
@@ -221,8 +221,8 @@ func (ppu *PPU) handleModeTransfer() {
 }
 
 func (ppu *PPU) incrementLy() {
-	ly := ppu.bus.readByte(LCD_LY)
-	wy := ppu.bus.readByte(LCD_WY)
+	ly := ppu.bus.peekByte(LCD_LY)
+	wy := ppu.bus.peekByte(LCD_WY)
 
 	if ppu.isWindowVisible() && ly >= wy && ly < wy+LCD_HEIGHT {
 		ppu.windowLine += 1
@@ -232,8 +232,8 @@ func (ppu *PPU) incrementLy() {
 }
 
 func (ppu *PPU) isWindowVisible() bool {
-	wx := ppu.bus.readByte(LCD_WX)
-	wy := ppu.bus.readByte(LCD_WY)
+	wx := ppu.bus.peekByte(LCD_WX)
+	wy := ppu.bus.peekByte(LCD_WY)
 
 	return ppu.lcd.IsWindowEnabled() && wx <= 166 && wy < LCD_HEIGHT
 }
@@ -242,9 +242,9 @@ func (ppu *PPU) handleModeVblank() {
 	if ppu.scanlineTicks >= DOTS_PER_LINE {
 		ppu.incrementLy()
 
-		if ppu.bus.readByte(LCD_LY) >= SCANLINES_PER_FRAME {
+		if ppu.bus.peekByte(LCD_LY) >= SCANLINES_PER_FRAME {
 			ppu.lcd.SetMode(LCD_MODE_OAM)
-			ppu.bus.writeByte(LCD_LY, 0)
+			ppu.bus.pokeByte(LCD_LY, 0)
 			ppu.windowLine = 0
 		}
 
@@ -256,7 +256,7 @@ func (ppu *PPU) handleModeHblank() {
 	if ppu.scanlineTicks >= DOTS_PER_LINE {
 		ppu.incrementLy()
 
-		if ppu.bus.readByte(LCD_LY) >= LCD_HEIGHT {
+		if ppu.bus.peekByte(LCD_LY) >= LCD_HEIGHT {
 			// If we're past the end of the screen, it's vblank time
 			ppu.lcd.SetMode(LCD_MODE_VBLANK)
 			// The CPU has a specific vblank interrupt
@@ -266,6 +266,7 @@ func (ppu *PPU) handleModeHblank() {
 				ppu.gameboy.RequestInterrupt(INT_LCD)
 			}
 			ppu.currentFrame++
+			ppu.gameboy.onVBlank()
 
 			ppu.fpsTimer.FrameEnd()
 			ppu.fpsTimer.FrameStart()
@@ -276,3 +277,32 @@ func (ppu *PPU) handleModeHblank() {
 		ppu.scanlineTicks = 0
 	}
 }
+
+func (ppu *PPU) saveState(s *stateWriter) {
+	ppu.lcd.saveState(s)
+
+	s.write(ppu.vram.data)
+	s.writeOamEntries(ppu.oam[:])
+	s.writeOamEntries(ppu.lineSprites)
+	s.write(ppu.windowLine, ppu.currentFrame, ppu.scanlineTicks, ppu.videoBuffer)
+
+	ppu.pixelFifo.saveState(s)
+}
+
+func (ppu *PPU) loadState(s *stateReader) {
+	ppu.lcd.loadState(s)
+
+	s.read(ppu.vram.data)
+
+	oam := s.readOamEntries()
+	if s.err == nil && len(oam) != len(ppu.oam) {
+		s.fail(ErrNotASaveState)
+		return
+	}
+	copy(ppu.oam[:], oam)
+
+	ppu.lineSprites = s.readOamEntries()
+	s.read(&ppu.windowLine, &ppu.currentFrame, &ppu.scanlineTicks, ppu.videoBuffer)
+
+	ppu.pixelFifo.loadState(s)
+}
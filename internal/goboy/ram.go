@@ -1,6 +1,9 @@
 package goboy
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
 
 type RAM struct {
 	// The size of the RAM bank in bytes
@@ -14,10 +17,6 @@ type RAM struct {
 }
 
 func NewRAM(size uint32, offset uint16) *RAM {
-	if DEBUG {
-		fmt.Printf("Creating RAM bank with size 0x%4.4X and offset 0x%4.4X\n", size, offset)
-	}
-
 	return &RAM{
 		size:   size,
 		offset: offset,
@@ -34,31 +33,40 @@ func (ram *RAM) writeByte(address uint16, value byte) {
 }
 
 func (ram *RAM) debugPrint() {
+	fmt.Print(formatHexdump(ram.offset, ram.data))
+}
+
+// Formats data as rows of 16 hex bytes followed by their printable ASCII
+// characters, with each row labelled by its address
+func formatHexdump(offset uint16, data []byte) string {
 	const bytesPerRow = 16
 
-	for i := 0; i < len(ram.data); i += bytesPerRow {
-		out := fmt.Sprintf("%04X: ", i+int(ram.offset))
+	var out strings.Builder
+	for i := 0; i < len(data); i += bytesPerRow {
+		fmt.Fprintf(&out, "%04X: ", i+int(offset))
 
 		// Print the hex values
-		for j := 0; j < bytesPerRow && i+j < len(ram.data); j++ {
-			out += fmt.Sprintf("%02X ", ram.data[i+j])
+		for j := 0; j < bytesPerRow && i+j < len(data); j++ {
+			fmt.Fprintf(&out, "%02X ", data[i+j])
 		}
 
 		// Print spacing between hex values and ASCII characters
-		for j := len(ram.data[i:]); j < bytesPerRow; j++ {
-			out += "   "
+		for j := len(data[i:]); j < bytesPerRow; j++ {
+			out.WriteString("   ")
 		}
 
 		// Print ASCII characters (if printable)
-		for j := 0; j < bytesPerRow && i+j < len(ram.data); j++ {
-			b := ram.data[i+j]
+		for j := 0; j < bytesPerRow && i+j < len(data); j++ {
+			b := data[i+j]
 			if b >= 32 && b <= 126 { // Printable ASCII range
-				out += fmt.Sprintf("%c", b)
+				out.WriteByte(b)
 			} else {
-				out += "."
+				out.WriteByte('.')
 			}
 		}
 
-		fmt.Println(out)
+		out.WriteByte('\n')
 	}
+
+	return out.String()
 }
@@ -0,0 +1,247 @@
+package goboy
+
+import (
+	"encoding/binary"
+	"time"
+)
+
+// @see https://gbdev.io/pandocs/MBC3.html#the-clock-counter-registers
+const (
+	RTC_S  = 0x08 // seconds 0-59
+	RTC_M  = 0x09 // minutes 0-59
+	RTC_H  = 0x0A // hours 0-23
+	RTC_DL = 0x0B // lower 8 bits of the day counter
+	RTC_DH = 0x0C // upper bit of the day counter, halt flag and day carry
+)
+
+const (
+	RTC_DH_DAY_HI    = 0
+	RTC_DH_HALT      = 6
+	RTC_DH_DAY_CARRY = 7
+)
+
+// Battery saves for cartridges with a clock have this many bytes tacked on the
+// end. The layout is shared by most emulators (VBA-M, BGB, mGBA, ...): the five
+// clock registers, the five latched registers (each as a little endian uint32)
+// and then a 64 bit unix timestamp of when the save was written. Some older
+// emulators write a 32 bit timestamp, giving a 44 byte footer instead
+const (
+	RTC_SAVE_FOOTER_SIZE     = 48
+	RTC_SAVE_FOOTER_SIZE_OLD = 44
+)
+
+var RTC_CARTRIDGE_TYPES = map[byte]bool{
+	0x0F: true, // MBC3+TIMER+BATTERY
+	0x10: true, // MBC3+TIMER+RAM+BATTERY
+}
+
+// The MBC3 real time clock keeps counting even when the Game Boy is switched
+// off, so rather than counting emulated cycles it follows the wall clock
+type RTC struct {
+	seconds  byte
+	minutes  byte
+	hours    byte
+	days     uint16
+	halted   bool
+	dayCarry bool
+
+	// Games read a snapshot of the clock taken by writing 0 then 1 to
+	// 0x6000-0x7FFF, rather than the live registers
+	latched        [5]byte
+	lastLatchWrite byte
+
+	// The point in time the registers were last brought up to date. Any time
+	// which has passed since then hasn't been added to the registers yet
+	syncedAt time.Time
+	now      func() time.Time
+}
+
+func NewRTC() *RTC {
+	return &RTC{
+		lastLatchWrite: 0xFF,
+		syncedAt:       time.Now(),
+		now:            time.Now,
+	}
+}
+
+// Brings the registers up to date with the wall clock. Any part of a second
+// left over stays in syncedAt so it isn't lost
+func (rtc *RTC) sync() {
+	now := rtc.now()
+
+	if rtc.halted {
+		rtc.syncedAt = now
+		return
+	}
+
+	elapsed := now.Sub(rtc.syncedAt)
+	if elapsed < time.Second {
+		if elapsed < 0 {
+			rtc.syncedAt = now
+		}
+		return
+	}
+
+	seconds := int64(elapsed / time.Second)
+	rtc.advance(seconds)
+	rtc.syncedAt = rtc.syncedAt.Add(time.Duration(seconds) * time.Second)
+}
+
+func (rtc *RTC) valid() bool {
+	return rtc.seconds < 60 && rtc.minutes < 60 && rtc.hours < 24
+}
+
+func (rtc *RTC) advance(seconds int64) {
+	// Games can write out of range values to the registers, in which case the
+	// counters carry on until they overflow their bits instead of carrying, so
+	// step through those one second at a time
+	for ; seconds > 0 && !rtc.valid(); seconds-- {
+		rtc.tick()
+	}
+
+	if seconds == 0 {
+		return
+	}
+
+	total := seconds +
+		int64(rtc.seconds) +
+		int64(rtc.minutes)*60 +
+		int64(rtc.hours)*60*60 +
+		int64(rtc.days)*60*60*24
+
+	rtc.seconds = byte(total % 60)
+	total /= 60
+	rtc.minutes = byte(total % 60)
+	total /= 60
+	rtc.hours = byte(total % 24)
+	total /= 24
+
+	// The day counter is only 9 bits, and sets the carry bit when it overflows.
+	// The carry stays set until the game clears it
+	if total > 0x1FF {
+		rtc.dayCarry = true
+	}
+	rtc.days = uint16(total & 0x1FF)
+}
+
+func (rtc *RTC) tick() {
+	rtc.seconds = (rtc.seconds + 1) & 0x3F
+	if rtc.seconds != 60 {
+		return
+	}
+	rtc.seconds = 0
+
+	rtc.minutes = (rtc.minutes + 1) & 0x3F
+	if rtc.minutes != 60 {
+		return
+	}
+	rtc.minutes = 0
+
+	rtc.hours = (rtc.hours + 1) & 0x1F
+	if rtc.hours != 24 {
+		return
+	}
+	rtc.hours = 0
+
+	rtc.days = (rtc.days + 1) & 0x1FF
+	if rtc.days == 0 {
+		rtc.dayCarry = true
+	}
+}
+
+func (rtc *RTC) registers() [5]byte {
+	dh := byte(rtc.days>>8) & 0x01
+	dh = SetBit(dh, RTC_DH_HALT, rtc.halted)
+	dh = SetBit(dh, RTC_DH_DAY_CARRY, rtc.dayCarry)
+
+	return [5]byte{rtc.seconds, rtc.minutes, rtc.hours, byte(rtc.days), dh}
+}
+
+func (rtc *RTC) setRegisters(registers [5]byte) {
+	rtc.seconds = registers[0] & 0x3F
+	rtc.minutes = registers[1] & 0x3F
+	rtc.hours = registers[2] & 0x1F
+	rtc.days = uint16(registers[3]) | uint16(registers[4]&0x01)<<8
+	rtc.halted = GetBit(registers[4], RTC_DH_HALT)
+	rtc.dayCarry = GetBit(registers[4], RTC_DH_DAY_CARRY)
+}
+
+func (rtc *RTC) latch(value byte) {
+	if rtc.lastLatchWrite == 0x00 && value == 0x01 {
+		rtc.sync()
+		rtc.latched = rtc.registers()
+	}
+
+	rtc.lastLatchWrite = value
+}
+
+func (rtc *RTC) readRegister(index byte) byte {
+	return rtc.latched[index-RTC_S]
+}
+
+func (rtc *RTC) writeRegister(index byte, value byte) {
+	rtc.sync()
+
+	registers := rtc.registers()
+	registers[index-RTC_S] = value
+	rtc.setRegisters(registers)
+
+	// Writing the seconds register also resets the part of a second that has
+	// passed so far
+	if index == RTC_S {
+		rtc.syncedAt = rtc.now()
+	}
+}
+
+func (rtc *RTC) saveFooter() []byte {
+	rtc.sync()
+
+	footer := make([]byte, 0, RTC_SAVE_FOOTER_SIZE)
+	for _, register := range rtc.registers() {
+		footer = binary.LittleEndian.AppendUint32(footer, uint32(register))
+	}
+	for _, register := range rtc.latched {
+		footer = binary.LittleEndian.AppendUint32(footer, uint32(register))
+	}
+
+	return binary.LittleEndian.AppendUint64(footer, uint64(rtc.syncedAt.Unix()))
+}
+
+// Restores the clock from a save footer, and then catches it up with however
+// much time has passed since the save was written
+func (rtc *RTC) loadFooter(footer []byte) {
+	var registers [5]byte
+	for i := range registers {
+		registers[i] = byte(binary.LittleEndian.Uint32(footer[i*4:]))
+	}
+	for i := range rtc.latched {
+		rtc.latched[i] = byte(binary.LittleEndian.Uint32(footer[20+i*4:]))
+	}
+
+	var timestamp int64
+	if len(footer) >= RTC_SAVE_FOOTER_SIZE {
+		timestamp = int64(binary.LittleEndian.Uint64(footer[40:]))
+	} else {
+		timestamp = int64(binary.LittleEndian.Uint32(footer[40:]))
+	}
+
+	rtc.setRegisters(registers)
+	rtc.syncedAt = time.Unix(timestamp, 0)
+	rtc.sync()
+}
+
+func (rtc *RTC) saveState(s *stateWriter) {
+	rtc.sync()
+
+	s.write(rtc.registers(), rtc.latched, rtc.lastLatchWrite, rtc.syncedAt.UnixNano())
+}
+
+func (rtc *RTC) loadState(s *stateReader) {
+	var registers [5]byte
+	var syncedAt int64
+	s.read(&registers, &rtc.latched, &rtc.lastLatchWrite, &syncedAt)
+
+	rtc.setRegisters(registers)
+	rtc.syncedAt = time.Unix(0, syncedAt)
+	rtc.sync()
+}
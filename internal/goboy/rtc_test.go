@@ -0,0 +1,156 @@
+package goboy
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (clock *fakeClock) Now() time.Time {
+	return clock.now
+}
+
+func (clock *fakeClock) Advance(d time.Duration) {
+	clock.now = clock.now.Add(d)
+}
+
+func newTestRTC() (*RTC, *fakeClock) {
+	clock := &fakeClock{now: time.Unix(1_700_000_000, 0)}
+	rtc := NewRTC()
+	rtc.now = clock.Now
+	rtc.syncedAt = clock.now
+
+	return rtc, clock
+}
+
+func latchTestRTC(rtc *RTC) [5]byte {
+	rtc.latch(0x00)
+	rtc.latch(0x01)
+
+	return rtc.latched
+}
+
+func TestRTC_CountsWallClockTime(t *testing.T) {
+	rtc, clock := newTestRTC()
+
+	clock.Advance(3*24*time.Hour + 4*time.Hour + 5*time.Minute + 6*time.Second + 500*time.Millisecond)
+
+	expected := [5]byte{6, 5, 4, 3, 0}
+	if latched := latchTestRTC(rtc); latched != expected {
+		t.Fatalf("expected %v, got %v", expected, latched)
+	}
+
+	// The half second left over shouldn't be lost
+	clock.Advance(500 * time.Millisecond)
+	if latched := latchTestRTC(rtc); latched[0] != 7 {
+		t.Fatalf("expected 7 seconds, got %d", latched[0])
+	}
+}
+
+func TestRTC_OnlyLatchesOnZeroThenOne(t *testing.T) {
+	rtc, clock := newTestRTC()
+	latchTestRTC(rtc)
+
+	clock.Advance(10 * time.Second)
+	rtc.latch(0x01)
+
+	if rtc.readRegister(RTC_S) != 0 {
+		t.Fatal("expected writing 1 without a preceding 0 not to latch")
+	}
+
+	rtc.latch(0x00)
+	rtc.latch(0x01)
+
+	if rtc.readRegister(RTC_S) != 10 {
+		t.Fatalf("expected 10 seconds after latching, got %d", rtc.readRegister(RTC_S))
+	}
+}
+
+func TestRTC_DayCarry(t *testing.T) {
+	rtc, clock := newTestRTC()
+	rtc.writeRegister(RTC_DL, 0xFF)
+	rtc.writeRegister(RTC_DH, 0x01)
+	rtc.writeRegister(RTC_H, 23)
+	rtc.writeRegister(RTC_M, 59)
+	rtc.writeRegister(RTC_S, 59)
+
+	clock.Advance(time.Second)
+	latched := latchTestRTC(rtc)
+
+	if latched[RTC_DL-RTC_S] != 0 || !GetBit(latched[RTC_DH-RTC_S], RTC_DH_DAY_CARRY) {
+		t.Fatalf("expected days to wrap and set the carry, got %v", latched)
+	}
+}
+
+func TestRTC_Halt(t *testing.T) {
+	rtc, clock := newTestRTC()
+	rtc.writeRegister(RTC_DH, 1<<RTC_DH_HALT)
+
+	clock.Advance(time.Hour)
+	if latched := latchTestRTC(rtc); latched[RTC_H-RTC_S] != 0 {
+		t.Fatalf("expected a halted clock not to count, got %v", latched)
+	}
+
+	rtc.writeRegister(RTC_DH, 0)
+	clock.Advance(time.Minute)
+	if latched := latchTestRTC(rtc); latched[RTC_M-RTC_S] != 1 || latched[RTC_H-RTC_S] != 0 {
+		t.Fatalf("expected the clock to resume after halting, got %v", latched)
+	}
+}
+
+func TestRTC_PersistsWithBatterySave(t *testing.T) {
+	// MBC3+TIMER+RAM+BATTERY with 8 KiB of RAM
+	romPath := writeTestRom(t, 0x10, 0x02, 0x02)
+	cartridge, err := LoadCartridge(romPath, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clock := &fakeClock{now: time.Now()}
+	cartridge.rtc.now = clock.Now
+	cartridge.rtc.syncedAt = clock.now
+
+	cartridge.writeByte(0x0000, 0x0A)
+	cartridge.writeByte(0x4000, RTC_H)
+	cartridge.writeByte(0xA000, 5)
+
+	if err := cartridge.SaveBattery(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(cartridge.savePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(data) != 0x2000+RTC_SAVE_FOOTER_SIZE {
+		t.Fatalf("expected RAM followed by a 48 byte clock footer, got %d bytes", len(data))
+	}
+
+	// Pretend the save was written two hours ago
+	twoHoursAgo := time.Now().Add(-2 * time.Hour).Unix()
+	for i := 0; i < 8; i++ {
+		data[0x2000+40+i] = byte(twoHoursAgo >> (8 * i))
+	}
+	if err := os.WriteFile(cartridge.savePath, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	reloaded, err := LoadCartridge(romPath, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reloaded.writeByte(0x0000, 0x0A)
+	reloaded.writeByte(0x6000, 0x00)
+	reloaded.writeByte(0x6000, 0x01)
+	reloaded.writeByte(0x4000, RTC_H)
+
+	if hours := reloaded.readByte(0xA000); hours != 7 {
+		t.Fatalf("expected elapsed time to be applied on load (7 hours), got %d", hours)
+	}
+}
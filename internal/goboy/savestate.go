@@ -0,0 +1,261 @@
+package goboy
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// Save states are a snapshot of the entire machine. They start with a magic
+// string and a version number so that states written by older versions of the
+// emulator can either be upgraded as they're loaded or rejected cleanly,
+// rather than being loaded as garbage
+const (
+	SAVE_STATE_MAGIC   = "GBST"
+	SAVE_STATE_VERSION = uint16(7)
+)
+
+var ErrNotASaveState = errors.New("not a save state")
+var ErrSaveStateRomMismatch = errors.New("save state was made with a different ROM")
+var ErrSaveStateNeedsBootRom = errors.New("save state was made while running the boot ROM")
+
+type UnsupportedSaveStateVersionError struct {
+	Version uint16
+}
+
+func (e UnsupportedSaveStateVersionError) Error() string {
+	return fmt.Sprintf("unsupported save state version %d (current version is %d)", e.Version, SAVE_STATE_VERSION)
+}
+
+// Saving happens field by field, stopping at the first error so that every
+// component doesn't need to check errors itself
+type stateWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (s *stateWriter) write(values ...any) {
+	for _, value := range values {
+		if s.err != nil {
+			return
+		}
+
+		s.err = binary.Write(s.w, binary.LittleEndian, value)
+	}
+}
+
+// Readers know the version of the state being loaded so that components can
+// skip anything which didn't exist when the state was written
+type stateReader struct {
+	r       io.Reader
+	version uint16
+	err     error
+}
+
+func (s *stateReader) read(values ...any) {
+	for _, value := range values {
+		if s.err != nil {
+			return
+		}
+
+		s.err = binary.Read(s.r, binary.LittleEndian, value)
+	}
+}
+
+func (s *stateReader) fail(err error) {
+	if s.err == nil {
+		s.err = err
+	}
+}
+
+func (s *stateWriter) writeOamEntries(entries []OamEntry) {
+	s.write(uint16(len(entries)))
+	for _, entry := range entries {
+		s.write(entry.y, entry.x, entry.tile, entry.flags)
+	}
+}
+
+func (s *stateReader) readOamEntries() []OamEntry {
+	var count uint16
+	s.read(&count)
+
+	if s.err != nil || count > 40 {
+		s.fail(ErrNotASaveState)
+		return nil
+	}
+
+	entries := make([]OamEntry, count)
+	for i := range entries {
+		s.read(&entries[i].y, &entries[i].x, &entries[i].tile, &entries[i].flags)
+	}
+
+	return entries
+}
+
+// Writes the complete state of the machine. This must not be called while the
+// GameBoy is running on another goroutine, use SaveStateSlot for that instead.
+func (gameboy *GameBoy) SaveState(w io.Writer) error {
+	buffered := bufio.NewWriter(w)
+	s := &stateWriter{w: buffered}
+
+	s.write([]byte(SAVE_STATE_MAGIC), SAVE_STATE_VERSION)
+	gameboy.saveState(s)
+
+	if s.err != nil {
+		return fmt.Errorf("failed to save state: %w", s.err)
+	}
+
+	if err := buffered.Flush(); err != nil {
+		return fmt.Errorf("failed to save state: %w", err)
+	}
+
+	return nil
+}
+
+// Restores the complete state of the machine. If the state can't be loaded the
+// machine is left exactly as it was. This must not be called while the GameBoy
+// is running on another goroutine, use LoadStateSlot for that instead.
+func (gameboy *GameBoy) LoadState(r io.Reader) error {
+	s := &stateReader{r: bufio.NewReader(r)}
+
+	magic := make([]byte, len(SAVE_STATE_MAGIC))
+	s.read(magic, &s.version)
+
+	if s.err != nil || string(magic) != SAVE_STATE_MAGIC {
+		return fmt.Errorf("failed to load state: %w", ErrNotASaveState)
+	}
+
+	if s.version > SAVE_STATE_VERSION || s.version == 0 {
+		return fmt.Errorf("failed to load state: %w", UnsupportedSaveStateVersionError{Version: s.version})
+	}
+
+	// Loading overwrites components one at a time, so keep a copy of the current
+	// state around to put back if anything goes wrong part way through
+	var backup bytes.Buffer
+	if err := gameboy.SaveState(&backup); err != nil {
+		return err
+	}
+
+	gameboy.loadState(s)
+
+	if s.err != nil {
+		if errors.Is(s.err, io.EOF) || errors.Is(s.err, io.ErrUnexpectedEOF) {
+			s.err = ErrNotASaveState
+		}
+
+		if err := gameboy.LoadState(&backup); err != nil {
+			panic(fmt.Sprintf("failed to restore state after failed load: %v", err))
+		}
+
+		return fmt.Errorf("failed to load state: %w", s.err)
+	}
+
+	return nil
+}
+
+func (gameboy *GameBoy) saveState(s *stateWriter) {
+	s.write(gameboy.cycles)
+
+	gameboy.cartridge.saveState(s)
+	gameboy.cpu.saveState(s)
+	gameboy.timer.saveState(s)
+	gameboy.io.dma.saveState(s)
+	gameboy.ppu.saveState(s)
+	gameboy.apu.saveState(s)
+	gameboy.io.serial.saveState(s)
+	gameboy.joypad.saveState(s)
+
+	bus := gameboy.bus.(*Bus)
+	s.write(bus.wram.data, bus.hram.data)
+	s.write(bus.interruptEnableRegister.data, gameboy.io.interrupts.data)
+	s.write(bus.bootRom != nil)
+}
+
+func (gameboy *GameBoy) loadState(s *stateReader) {
+	s.read(&gameboy.cycles)
+
+	gameboy.cartridge.loadState(s)
+	gameboy.cpu.loadState(s)
+	gameboy.timer.loadState(s)
+	gameboy.io.dma.loadState(s)
+	gameboy.ppu.loadState(s)
+	gameboy.apu.loadState(s)
+	gameboy.io.serial.loadState(s)
+	gameboy.joypad.loadState(s)
+
+	bus := gameboy.bus.(*Bus)
+	s.read(bus.wram.data, bus.hram.data)
+	s.read(&bus.interruptEnableRegister.data, &gameboy.io.interrupts.data)
+
+	// Before version 4 there was no boot ROM, so it was never mapped
+	var bootRomMapped bool
+	if s.version >= 4 {
+		s.read(&bootRomMapped)
+	}
+
+	if s.err == nil && bootRomMapped && gameboy.bootRom == nil {
+		s.fail(ErrSaveStateNeedsBootRom)
+		return
+	}
+
+	bus.bootRom = nil
+	if bootRomMapped {
+		bus.bootRom = gameboy.bootRom
+	}
+}
+
+// Slots are numbered save state files kept alongside battery saves
+func (gameboy *GameBoy) saveStateSlotPath(slot int) string {
+	return savePathFor(gameboy.options.RomPath, gameboy.options.SaveDir, fmt.Sprintf(".ss%d", slot))
+}
+
+// Queues up a save to the given slot, which happens between instructions on
+// the emulator goroutine
+func (gameboy *GameBoy) SaveStateSlot(slot int) {
+	gameboy.queueCommand(func() {
+		path := gameboy.saveStateSlotPath(slot)
+
+		var buffer bytes.Buffer
+		err := gameboy.SaveState(&buffer)
+
+		if err == nil {
+			err = os.MkdirAll(filepath.Dir(path), 0700)
+		}
+		if err == nil {
+			err = os.WriteFile(path, buffer.Bytes(), 0600)
+		}
+
+		if err != nil {
+			fmt.Printf("Failed to save state to slot %d: %v\n", slot, err)
+			return
+		}
+
+		fmt.Printf("Saved state to slot %d (%s)\n", slot, path)
+	})
+}
+
+// Queues up a load from the given slot, which happens between instructions on
+// the emulator goroutine
+func (gameboy *GameBoy) LoadStateSlot(slot int) {
+	gameboy.queueCommand(func() {
+		path := gameboy.saveStateSlotPath(slot)
+
+		file, err := os.Open(path)
+		if err == nil {
+			err = gameboy.LoadState(file)
+			file.Close()
+		}
+
+		if err != nil {
+			fmt.Printf("Failed to load state from slot %d: %v\n", slot, err)
+			return
+		}
+
+		fmt.Printf("Loaded state from slot %d (%s)\n", slot, path)
+	})
+}
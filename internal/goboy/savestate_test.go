@@ -0,0 +1,114 @@
+package goboy
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func tickTestGameBoy(gameboy *GameBoy, instructions int) {
+	for i := 0; i < instructions; i++ {
+		gameboy.cpu.Tick()
+	}
+}
+
+func TestSaveState_RoundTrip(t *testing.T) {
+	gameboy := newTestGameBoy(t)
+	tickTestGameBoy(gameboy, 50000)
+
+	var state bytes.Buffer
+	if err := gameboy.SaveState(&state); err != nil {
+		t.Fatal(err)
+	}
+
+	// Run on from the saved point and remember where we ended up
+	tickTestGameBoy(gameboy, 50000)
+	var expected bytes.Buffer
+	if err := gameboy.SaveState(&expected); err != nil {
+		t.Fatal(err)
+	}
+
+	// Loading the state and running the same number of instructions should put
+	// the machine in exactly the same place
+	if err := gameboy.LoadState(bytes.NewReader(state.Bytes())); err != nil {
+		t.Fatal(err)
+	}
+	tickTestGameBoy(gameboy, 50000)
+
+	var actual bytes.Buffer
+	if err := gameboy.SaveState(&actual); err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(expected.Bytes(), actual.Bytes()) {
+		t.Fatal("expected restored machine to run identically to the original")
+	}
+}
+
+func TestLoadState_RejectsGarbage(t *testing.T) {
+	gameboy := newTestGameBoy(t)
+
+	err := gameboy.LoadState(bytes.NewReader([]byte("definitely not a state")))
+	if !errors.Is(err, ErrNotASaveState) {
+		t.Fatalf("expected ErrNotASaveState, got %v", err)
+	}
+}
+
+func TestLoadState_RejectsNewerVersions(t *testing.T) {
+	gameboy := newTestGameBoy(t)
+
+	var state bytes.Buffer
+	if err := gameboy.SaveState(&state); err != nil {
+		t.Fatal(err)
+	}
+
+	data := state.Bytes()
+	data[len(SAVE_STATE_MAGIC)] = byte(SAVE_STATE_VERSION + 1)
+
+	var versionErr UnsupportedSaveStateVersionError
+	if err := gameboy.LoadState(bytes.NewReader(data)); !errors.As(err, &versionErr) {
+		t.Fatalf("expected UnsupportedSaveStateVersionError, got %v", err)
+	}
+}
+
+func TestLoadState_TruncatedLeavesMachineUntouched(t *testing.T) {
+	gameboy := newTestGameBoy(t)
+	tickTestGameBoy(gameboy, 1000)
+
+	var state bytes.Buffer
+	if err := gameboy.SaveState(&state); err != nil {
+		t.Fatal(err)
+	}
+
+	tickTestGameBoy(gameboy, 1000)
+	pc := gameboy.cpu.registers.pc
+
+	truncated := state.Bytes()[:state.Len()/2]
+	if err := gameboy.LoadState(bytes.NewReader(truncated)); !errors.Is(err, ErrNotASaveState) {
+		t.Fatalf("expected ErrNotASaveState, got %v", err)
+	}
+
+	if gameboy.cpu.registers.pc != pc {
+		t.Errorf("expected PC to be restored to 0x%4.4X, got 0x%4.4X", pc, gameboy.cpu.registers.pc)
+	}
+}
+
+func TestLoadState_RejectsOtherRoms(t *testing.T) {
+	gameboy := newTestGameBoy(t)
+
+	var state bytes.Buffer
+	if err := gameboy.SaveState(&state); err != nil {
+		t.Fatal(err)
+	}
+
+	options := DefaultOptions()
+	options.RomPath = writeTestRom(t, 0x00, 0x00, 0x00)
+	other, err := NewGameBoy(options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := other.LoadState(&state); !errors.Is(err, ErrSaveStateRomMismatch) {
+		t.Fatalf("expected ErrSaveStateRomMismatch, got %v", err)
+	}
+}
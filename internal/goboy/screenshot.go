@@ -0,0 +1,100 @@
+package goboy
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"os"
+)
+
+// Screenshots only keep which of the 4 DMG shades each pixel is, so they can be
+// compared against reference images made with a different palette
+var SCREENSHOT_PALETTE = color.Palette{
+	color.Gray{Y: 0xFF},
+	color.Gray{Y: 0xAA},
+	color.Gray{Y: 0x55},
+	color.Gray{Y: 0x00},
+}
+
+// Maps a color to the closest of the 4 DMG shades, lightest first
+func shadeOf(c color.Color) uint8 {
+	return uint8(SCREENSHOT_PALETTE.Index(color.GrayModel.Convert(c)))
+}
+
+// Captures the PPU's video buffer. This is only a complete frame straight after
+// the PPU enters VBlank.
+func (gameboy *GameBoy) Screenshot() *image.Paletted {
+	screenshot := image.NewPaletted(image.Rect(0, 0, LCD_WIDTH, LCD_HEIGHT), SCREENSHOT_PALETTE)
+
+	for i, pixel := range gameboy.ppu.videoBuffer {
+		screenshot.Pix[i] = shadeOf(color.RGBA{
+			R: byte(pixel >> 16),
+			G: byte(pixel >> 8),
+			B: byte(pixel),
+			A: byte(pixel >> 24),
+		})
+	}
+
+	return screenshot
+}
+
+// Runs a ROM headlessly for maxFrames frames, or until it runs LD B, B, and
+// captures the last frame. Test ROMs such as dmg-acid2 use LD B, B to signal
+// they've finished drawing.
+func RunScreenshot(path string, maxFrames int) (*image.Paletted, error) {
+	romData, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to take screenshot: %w", err)
+	}
+
+	options := DefaultOptions()
+	options.Headless = true
+	options.Speed = 0
+
+	gameboy, err := NewGameBoyFromRom(options, romData, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to take screenshot: %w", err)
+	}
+
+	target := uint32(maxFrames)
+	gameboy.RegisterBreakpointCallback(func() {
+		// Let the frame being drawn finish
+		target = min(target, gameboy.FrameCount()+1)
+	})
+
+	for gameboy.FrameCount() < target && !gameboy.InStopMode() {
+		gameboy.Step()
+	}
+
+	return gameboy.Screenshot(), nil
+}
+
+// Compares two images by DMG shade. Returns how many pixels differ, and an
+// image with the differences in red over a faded copy of the reference.
+func CompareScreenshots(actual image.Image, reference image.Image) (int, *image.RGBA, error) {
+	bounds := reference.Bounds()
+	if actual.Bounds().Size() != bounds.Size() {
+		return 0, nil, fmt.Errorf("screenshot is %v, expected %v", actual.Bounds().Size(), bounds.Size())
+	}
+
+	offset := actual.Bounds().Min.Sub(bounds.Min)
+	diff := image.NewRGBA(image.Rectangle{Max: bounds.Size()})
+	mismatches := 0
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			expected := shadeOf(reference.At(x, y))
+			got := shadeOf(actual.At(x+offset.X, y+offset.Y))
+
+			if expected == got {
+				faded := 0xC0 + SCREENSHOT_PALETTE[expected].(color.Gray).Y/4
+				diff.Set(x-bounds.Min.X, y-bounds.Min.Y, color.RGBA{R: faded, G: faded, B: faded, A: 0xFF})
+			} else {
+				diff.Set(x-bounds.Min.X, y-bounds.Min.Y, color.RGBA{R: 0xFF, A: 0xFF})
+				mismatches++
+			}
+		}
+	}
+
+	return mismatches, diff, nil
+}
@@ -0,0 +1,137 @@
+package goboy
+
+import (
+	"flag"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var updateScreenshots = flag.Bool("update-screenshots", false, "write new reference screenshots instead of comparing against them")
+
+// ROMs which aren't in the repo are skipped, so drop them in to data/ to run
+// them. Once the ROM is there its reference has to be too.
+//
+// Official references come with the ROM and are never overwritten by
+// -update-screenshots, the rest were generated by goboy.
+var SCREENSHOT_TESTS = []struct {
+	rom       string
+	reference string
+	frames    int
+	official  bool
+}{
+	// @see https://github.com/mattcurrie/dmg-acid2
+	{"data/roms/dmg-acid2/dmg-acid2.gb", "data/roms/dmg-acid2/reference-dmg.png", 300, true},
+	{"data/roms/blargg/01-special.gb", "data/screenshots/blargg/01-special.png", 200, false},
+	{"data/roms/blargg/06-ld r,r.gb", "data/screenshots/blargg/06-ld r,r.png", 60, false},
+}
+
+func TestScreenshots(t *testing.T) {
+	for _, test := range SCREENSHOT_TESTS {
+		t.Run(filepath.Base(test.rom), func(t *testing.T) {
+			if _, err := os.Stat(test.rom); err != nil {
+				t.Skipf("no ROM at %s", test.rom)
+			}
+
+			actual, err := RunScreenshot(test.rom, test.frames)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if *updateScreenshots && !test.official {
+				writeTestPng(t, test.reference, actual)
+				return
+			}
+
+			reference := readTestPng(t, test.reference)
+			mismatches, diff, err := CompareScreenshots(actual, reference)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if mismatches > 0 {
+				dir, err := os.MkdirTemp("", "goboy-screenshots")
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				name := filepath.Base(test.reference)
+				writeTestPng(t, filepath.Join(dir, "actual-"+name), actual)
+				writeTestPng(t, filepath.Join(dir, "diff-"+name), diff)
+
+				t.Errorf("%d pixels differ from %s, see %s", mismatches, test.reference, dir)
+			}
+		})
+	}
+}
+
+func TestCompareScreenshots(t *testing.T) {
+	reference := image.NewPaletted(image.Rect(0, 0, 4, 4), SCREENSHOT_PALETTE)
+
+	// A different palette with the same shades should still match
+	actual := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	for i := range actual.Pix {
+		actual.Pix[i] = 0xFE
+	}
+
+	mismatches, _, err := CompareScreenshots(actual, reference)
+	if err != nil || mismatches != 0 {
+		t.Fatalf("expected near white to match white, got %d mismatches (%v)", mismatches, err)
+	}
+
+	actual.Set(1, 2, color.Black)
+
+	mismatches, diff, err := CompareScreenshots(actual, reference)
+	if err != nil || mismatches != 1 {
+		t.Fatalf("expected 1 mismatch, got %d (%v)", mismatches, err)
+	}
+
+	if r, g, _, _ := diff.At(1, 2).RGBA(); r != 0xFFFF || g != 0 {
+		t.Errorf("expected the mismatch to be red in the diff")
+	}
+
+	if _, _, err := CompareScreenshots(image.NewRGBA(image.Rect(0, 0, 2, 2)), reference); err == nil {
+		t.Errorf("expected an error comparing different sizes")
+	}
+}
+
+func readTestPng(t *testing.T, path string) image.Image {
+	t.Helper()
+
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		t.Fatalf("no reference screenshot at %s", path)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	img, err := png.Decode(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return img
+}
+
+func writeTestPng(t *testing.T, path string, img image.Image) {
+	t.Helper()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	if err := png.Encode(f, img); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -1,7 +1,6 @@
 package goboy
 
 import (
-	"encoding/hex"
 	"fmt"
 	"os"
 )
@@ -17,9 +16,13 @@ const (
 	SC_TRANSFER_ENABLE = 7
 )
 
+// Called with each byte the Game Boy sends over the link cable
+type SerialCallback func(value byte)
+
 // @see https://gbdev.io/pandocs/Serial_Data_Transfer_(Link_Cable).html
 type Serial struct {
-	gameboy *GameBoy
+	gameboy   *GameBoy
+	callbacks []SerialCallback
 
 	sc byte
 	sb byte
@@ -67,7 +70,9 @@ func (serial *Serial) Tick() {
 		return
 	}
 
-	appendSerialToFile(serial.outgoingByte)
+	for _, callback := range serial.callbacks {
+		callback(serial.outgoingByte)
+	}
 	serial.transferredBits = 0
 	serial.outgoingByte = 0
 
@@ -103,20 +108,24 @@ func (serial *Serial) writeByte(address uint16, value byte) {
 	}
 }
 
-func appendSerialToFile(value byte) {
-	f, err := os.OpenFile("serial.out", os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0600)
+func appendSerialToFile(path string, value byte) {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0600)
 	if err != nil {
-		panic(err)
+		fmt.Println(err)
+		return
 	}
 
 	defer f.Close()
 
-	bs, err := hex.DecodeString(fmt.Sprintf("%2.2X", value))
-	if err != nil {
-		panic(err)
+	if _, err = f.Write([]byte{value}); err != nil {
+		fmt.Println(err)
 	}
+}
 
-	if _, err = f.WriteString(string(bs)); err != nil {
-		panic(err)
-	}
+func (serial *Serial) saveState(s *stateWriter) {
+	s.write(serial.sc, serial.sb, serial.transferredBits, serial.outgoingByte)
+}
+
+func (serial *Serial) loadState(s *stateReader) {
+	s.read(&serial.sc, &serial.sb, &serial.transferredBits, &serial.outgoingByte)
 }
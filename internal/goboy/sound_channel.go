@@ -358,3 +358,25 @@ func (sc *SoundChannel) readPeriodHigh() byte {
 
 	return value
 }
+
+func (sc *SoundChannel) saveState(s *stateWriter) {
+	s.write(sc.soundType, sc.enabled, sc.rightSpeakerOn, sc.leftSpeakerOn)
+	s.write(sc.envelopeDirection, sc.envelopeStartVolume, sc.envelopeSweepPace, sc.envelopeVolume, sc.envelopeCounter)
+	s.write(sc.t, sc.frequencyDivider, sc.period)
+	s.write(sc.sweepCounter, sc.sweepDirection, sc.sweepTime, sc.sweepShift)
+	s.write(sc.lengthData, sc.currentLength, sc.waveDuty, sc.waveDutySeqCounter)
+	s.write(sc.waveOutLvl, sc.wavePatternRAM, sc.wavePatternCursor)
+	s.write(sc.polyFeedbackReg, sc.polyDivisorShift, sc.polyDivisorBase, sc.poly7BitMode, sc.polySample)
+	s.write(sc.playsContinuously, sc.restartRequested)
+}
+
+func (sc *SoundChannel) loadState(s *stateReader) {
+	s.read(&sc.soundType, &sc.enabled, &sc.rightSpeakerOn, &sc.leftSpeakerOn)
+	s.read(&sc.envelopeDirection, &sc.envelopeStartVolume, &sc.envelopeSweepPace, &sc.envelopeVolume, &sc.envelopeCounter)
+	s.read(&sc.t, &sc.frequencyDivider, &sc.period)
+	s.read(&sc.sweepCounter, &sc.sweepDirection, &sc.sweepTime, &sc.sweepShift)
+	s.read(&sc.lengthData, &sc.currentLength, &sc.waveDuty, &sc.waveDutySeqCounter)
+	s.read(&sc.waveOutLvl, &sc.wavePatternRAM, &sc.wavePatternCursor)
+	s.read(&sc.polyFeedbackReg, &sc.polyDivisorShift, &sc.polyDivisorBase, &sc.poly7BitMode, &sc.polySample)
+	s.read(&sc.playsContinuously, &sc.restartRequested)
+}
@@ -0,0 +1,83 @@
+package goboy
+
+import "testing"
+
+// Selects the A, B, Select and Start line of the joypad
+const JOYPAD_SELECT_BUTTONS = 0x10
+
+func TestStop_WaitsForButtonPress(t *testing.T) {
+	// STOP; INC A
+	gameboy := newWramTestGameBoy(t, []byte{0x10, 0x00, 0x3C}, false)
+	gameboy.WriteMemory(IO_JOYP, JOYPAD_SELECT_BUTTONS)
+
+	gameboy.Step()
+	if !gameboy.InStopMode() {
+		t.Fatalf("expected STOP to stop the CPU")
+	}
+	if pc := gameboy.ReadRegister(R_PC); pc != WRAM_TEST_PROGRAM_START+2 {
+		t.Errorf("expected STOP to skip the next byte, PC is 0x%4.4X", pc)
+	}
+	if div := gameboy.ReadMemory(TIMER_DIV); div != 0 {
+		t.Errorf("expected STOP to reset DIV, got 0x%2.2X", div)
+	}
+
+	sysclk := gameboy.timer.sysclk
+	for range 100 {
+		if mCycles := gameboy.Step(); mCycles != 0 {
+			t.Fatalf("expected nothing to run while stopped, took %d M-cycles", mCycles)
+		}
+	}
+	if gameboy.timer.sysclk != sysclk {
+		t.Errorf("expected the timer not to tick while stopped")
+	}
+
+	gameboy.Press(JOYPAD_A)
+	gameboy.Step()
+	if gameboy.InStopMode() {
+		t.Fatalf("expected a button press to wake the CPU")
+	}
+	if a := gameboy.ReadRegister(R_A); a != 1 {
+		t.Errorf("expected to carry on after STOP, A is %d", a)
+	}
+}
+
+func TestStop_OnlySelectedLinesWake(t *testing.T) {
+	// STOP
+	gameboy := newWramTestGameBoy(t, []byte{0x10, 0x00}, false)
+	gameboy.WriteMemory(IO_JOYP, 0x30)
+
+	gameboy.Step()
+	gameboy.Press(JOYPAD_A)
+	gameboy.Step()
+
+	if !gameboy.InStopMode() {
+		t.Errorf("expected a button on an unselected line not to wake the CPU")
+	}
+}
+
+func TestStop_WithButtonHeldHalts(t *testing.T) {
+	// STOP
+	gameboy := newWramTestGameBoy(t, []byte{0x10, 0x00}, false)
+	gameboy.WriteMemory(IO_JOYP, JOYPAD_SELECT_BUTTONS)
+	gameboy.Press(JOYPAD_A)
+
+	gameboy.Step()
+	if gameboy.InStopMode() || !gameboy.cpu.halted {
+		t.Errorf("expected STOP to halt instead of stopping while a button is held")
+	}
+	if pc := gameboy.ReadRegister(R_PC); pc != WRAM_TEST_PROGRAM_START+2 {
+		t.Errorf("expected STOP to skip the next byte, PC is 0x%4.4X", pc)
+	}
+}
+
+func TestStop_NoSpeedSwitchOnDMG(t *testing.T) {
+	gameboy := newTestGameBoy(t)
+
+	if key1 := gameboy.ReadMemory(IO_KEY1); key1 != 0xFF {
+		t.Errorf("expected KEY1 to read 0xFF on the DMG, got 0x%2.2X", key1)
+	}
+
+	if unused := gameboy.ReadMemory(0xFF4C); unused != 0x00 {
+		t.Errorf("expected other unhandled registers to read 0, got 0x%2.2X", unused)
+	}
+}
@@ -0,0 +1,27 @@
+package goboy
+
+import (
+	"os"
+
+	"github.com/seashairo/goboy/internal/disasm"
+)
+
+// Loads the symbols at symbolPath, or if it's empty, the RGBDS symbol file next
+// to the ROM, e.g. game.sym for game.gb. It isn't an error for that one not to
+// exist, in which case there aren't any symbols.
+func LoadSymbolsFor(romPath string, symbolPath string) (*disasm.Symbols, error) {
+	if symbolPath != "" {
+		return disasm.LoadSymbols(symbolPath)
+	}
+
+	if romPath == "" {
+		return nil, nil
+	}
+
+	symbolPath = savePathFor(romPath, "", ".sym")
+	if _, err := os.Stat(symbolPath); os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	return disasm.LoadSymbols(symbolPath)
+}
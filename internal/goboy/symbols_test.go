@@ -0,0 +1,117 @@
+package goboy
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/seashairo/goboy/internal/disasm"
+)
+
+func TestLoadSymbolsFor_FindsTheFileNextToTheRom(t *testing.T) {
+	dir := t.TempDir()
+	romPath := filepath.Join(dir, "game.gb")
+
+	symbols, err := LoadSymbolsFor(romPath, "")
+	if err != nil || symbols != nil {
+		t.Fatalf("expected no symbols without a file, got %v, %v", symbols, err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "game.sym"), []byte("00:0150 Main\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	symbols, err = LoadSymbolsFor(romPath, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := symbols.Lookup("Main"); !ok {
+		t.Errorf("expected the symbols next to the ROM to be loaded")
+	}
+
+	if _, err := LoadSymbolsFor(romPath, filepath.Join(dir, "missing.sym")); err == nil {
+		t.Errorf("expected an error for a symbol file that was asked for but isn't there")
+	}
+}
+
+func newSymbolTestGameBoy(t *testing.T, program []byte, symbolText string) *GameBoy {
+	t.Helper()
+
+	symbols, err := disasm.ParseSymbols(strings.NewReader(symbolText))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gameboy := newWramTestGameBoy(t, program, false)
+	gameboy.SetSymbols(symbols)
+
+	return gameboy
+}
+
+func TestSymbols_Debugger(t *testing.T) {
+	gameboy := newSymbolTestGameBoy(t, DEBUGGER_TEST_PROGRAM, "00:c006 Loop\n00:c010 Increment\n00:c100 wValue\n")
+	out := &strings.Builder{}
+	debugger := NewDebugger(gameboy, out)
+	debugger.Run(strings.NewReader("break Increment\nwatch w wValue\ncontinue\ncontinue\ndis C000 1\n"))
+
+	if pc := gameboy.ReadRegister(R_PC); pc != 0xC006 {
+		t.Errorf("expected to stop after the write, PC is 0x%4.4X\n%s", pc, out)
+	}
+
+	for _, expected := range []string{
+		"Breakpoint 1 at Increment\n",
+		"Watchpoint 1: w C100\n",
+		"Breakpoint 1\nIncrement:\n=>    C010  3C        INC A\n",
+		"Watchpoint 1: write 0x01 at 0xC100\nLoop:\n=>    C006  04        INC B\n",
+		"      C000  CD 10 C0  CALL Increment\n",
+	} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("expected %q in\n%s", expected, out)
+		}
+	}
+}
+
+func TestSymbols_LockupFault(t *testing.T) {
+	gameboy := newSymbolTestGameBoy(t, []byte{0x00, 0x00, 0xD3}, "00:c000 Start\n")
+
+	var faults []LockupFault
+	gameboy.RegisterLockupCallback(func(fault LockupFault) {
+		faults = append(faults, fault)
+	})
+
+	for range 3 {
+		gameboy.Step()
+	}
+
+	if len(faults) != 1 || faults[0].Symbol != "Start+2" {
+		t.Fatalf("expected a fault at Start+2, got %+v", faults)
+	}
+
+	expected := "CPU locked up running invalid opcode 0xD3 at 0xC002 (Start+2)"
+	if faults[0].Error() != expected {
+		t.Errorf("expected %q, got %q", expected, faults[0].Error())
+	}
+}
+
+func TestSymbols_Trace(t *testing.T) {
+	gameboy := newSymbolTestGameBoy(t, DEBUGGER_TEST_PROGRAM, "00:c010 Increment\n")
+
+	options := DefaultTraceOptions()
+	options.Format = TRACE_FORMAT_JSON
+	out := &strings.Builder{}
+	tracer := NewTracer(out, options)
+	gameboy.SetTracer(tracer)
+
+	gameboy.Step()
+	gameboy.Step()
+	tracer.Flush()
+
+	lines := strings.Split(out.String(), "\n")
+	if !strings.Contains(lines[0], `"instruction":"CALL Increment"`) {
+		t.Errorf("expected the call to be labelled, got %s", lines[0])
+	}
+	if !strings.Contains(lines[1], `"symbol":"Increment"`) {
+		t.Errorf("expected PC to be labelled, got %s", lines[1])
+	}
+}
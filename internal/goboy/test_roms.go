@@ -0,0 +1,176 @@
+package goboy
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/tabwriter"
+)
+
+// Test ROMs report their results in one of two ways. Blargg's print "Passed" or
+// "Failed" over the serial port, and mooneye's run LD B, B with the Fibonacci
+// sequence in the registers to pass, or with every register set to 0x42 to fail.
+// @see https://github.com/retrio/gb-test-roms
+// @see https://github.com/Gekkio/mooneye-test-suite#passfail-reporting
+type TestRomResult byte
+
+const (
+	TEST_ROM_PASSED TestRomResult = iota
+	TEST_ROM_FAILED
+	TEST_ROM_TIMED_OUT
+	TEST_ROM_ERROR
+)
+
+func (result TestRomResult) String() string {
+	switch result {
+	case TEST_ROM_PASSED:
+		return "PASSED"
+	case TEST_ROM_FAILED:
+		return "FAILED"
+	case TEST_ROM_TIMED_OUT:
+		return "TIMED OUT"
+	default:
+		return "ERROR"
+	}
+}
+
+// Long enough for every ROM in blargg's cpu_instrs, including the combined one
+const DEFAULT_TEST_ROM_FRAME_BUDGET = 4000
+
+// B, C, D, E, H and L
+var MOONEYE_PASS_REGISTERS = [6]byte{3, 5, 8, 13, 21, 34}
+var MOONEYE_FAIL_REGISTERS = [6]byte{0x42, 0x42, 0x42, 0x42, 0x42, 0x42}
+
+type TestRomReport struct {
+	Path   string
+	Result TestRomResult
+	// How many frames ran before the ROM reported its result
+	Frames uint32
+	// Everything the ROM sent over the serial port
+	Serial string
+	// Why the ROM couldn't be run, for TEST_ROM_ERROR
+	Err error
+}
+
+// Runs a test ROM headlessly and as fast as possible until it reports a result,
+// or until it has run for frameBudget frames
+func RunTestRom(path string, frameBudget int) TestRomReport {
+	report := TestRomReport{Path: path, Result: TEST_ROM_TIMED_OUT}
+
+	romData, err := os.ReadFile(path)
+	if err != nil {
+		report.Result, report.Err = TEST_ROM_ERROR, err
+		return report
+	}
+
+	options := DefaultOptions()
+	options.Headless = true
+	options.Speed = 0
+
+	gameboy, err := NewGameBoyFromRom(options, romData, nil)
+	if err != nil {
+		report.Result, report.Err = TEST_ROM_ERROR, err
+		return report
+	}
+
+	finished := false
+	finish := func(result TestRomResult) {
+		report.Result = result
+		finished = true
+	}
+
+	var serial strings.Builder
+	gameboy.RegisterSerialCallback(func(value byte) {
+		serial.WriteByte(value)
+
+		if strings.Contains(serial.String(), "Passed") {
+			finish(TEST_ROM_PASSED)
+		} else if strings.Contains(serial.String(), "Failed") {
+			finish(TEST_ROM_FAILED)
+		}
+	})
+
+	// Nothing else will happen after a lockup, so there's no point waiting for
+	// the rest of the frame budget
+	gameboy.RegisterLockupCallback(func(fault LockupFault) {
+		report.Err = fault
+		finish(TEST_ROM_ERROR)
+	})
+
+	gameboy.RegisterBreakpointCallback(func() {
+		switch gameboy.mooneyeRegisters() {
+		case MOONEYE_PASS_REGISTERS:
+			finish(TEST_ROM_PASSED)
+		case MOONEYE_FAIL_REGISTERS:
+			finish(TEST_ROM_FAILED)
+		}
+	})
+
+	// Nothing presses any buttons, so a ROM that runs STOP will never finish
+	for !finished && !gameboy.InStopMode() && gameboy.FrameCount() < uint32(frameBudget) {
+		gameboy.Step()
+	}
+
+	report.Frames = gameboy.FrameCount()
+	report.Serial = serial.String()
+
+	return report
+}
+
+func (gameboy *GameBoy) mooneyeRegisters() [6]byte {
+	registers := [6]byte{}
+	for i, register := range []CpuRegister{R_B, R_C, R_D, R_E, R_H, R_L} {
+		registers[i] = byte(gameboy.ReadRegister(register))
+	}
+
+	return registers
+}
+
+// Runs every .gb and .gbc file in a directory, in name order
+func RunTestRoms(dir string, frameBudget int) ([]TestRomReport, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find test ROMs: %w", err)
+	}
+
+	paths := make([]string, 0)
+	for _, entry := range entries {
+		extension := strings.ToLower(filepath.Ext(entry.Name()))
+		if !entry.IsDir() && (extension == ".gb" || extension == ".gbc") {
+			paths = append(paths, filepath.Join(dir, entry.Name()))
+		}
+	}
+	slices.Sort(paths)
+
+	reports := make([]TestRomReport, 0, len(paths))
+	for _, path := range paths {
+		reports = append(reports, RunTestRom(path, frameBudget))
+	}
+
+	return reports, nil
+}
+
+func PrintTestRomReports(w io.Writer, reports []TestRomReport) {
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "ROM\tRESULT\tFRAMES\t")
+
+	passed := 0
+	for _, report := range reports {
+		result := report.Result.String()
+		if report.Err != nil {
+			result = fmt.Sprintf("%s (%v)", result, report.Err)
+		}
+
+		fmt.Fprintf(table, "%s\t%s\t%d\t\n", filepath.Base(report.Path), result, report.Frames)
+
+		if report.Result == TEST_ROM_PASSED {
+			passed++
+		}
+	}
+
+	table.Flush()
+	fmt.Fprintf(w, "\n%d/%d passed\n", passed, len(reports))
+}
@@ -0,0 +1,102 @@
+package goboy
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBlarggCpuInstrs(t *testing.T) {
+	if testing.Short() {
+		t.Skip("test ROMs take a while to run")
+	}
+
+	paths, _ := filepath.Glob("data/roms/blargg/*.gb")
+	if len(paths) == 0 {
+		t.Skip("no blargg test ROMs found")
+	}
+
+	for _, path := range paths {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			t.Parallel()
+
+			report := RunTestRom(path, DEFAULT_TEST_ROM_FRAME_BUDGET)
+			if report.Result != TEST_ROM_PASSED {
+				t.Errorf("%s after %d frames:\n%s", report.Result, report.Frames, report.Serial)
+			}
+		})
+	}
+}
+
+// Builds a ROM which runs the given code from the cartridge entry point
+func writeTestRomWithCode(t *testing.T, code []byte) string {
+	t.Helper()
+
+	path := writeTestRom(t, 0x00, 0x00, 0x00)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	copy(data[0x0100:], code)
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestRunTestRom_MooneyeRegisters(t *testing.T) {
+	loadRegisters := func(values [6]byte) []byte {
+		return []byte{
+			0x06, values[0], // LD B, n
+			0x0E, values[1], // LD C, n
+			0x16, values[2], // LD D, n
+			0x1E, values[3], // LD E, n
+			0x26, values[4], // LD H, n
+			0x2E, values[5], // LD L, n
+			0x40,       // LD B, B
+			0x18, 0xFE, // JR -2
+		}
+	}
+
+	tests := []struct {
+		name     string
+		code     []byte
+		expected TestRomResult
+	}{
+		{"pass", loadRegisters(MOONEYE_PASS_REGISTERS), TEST_ROM_PASSED},
+		{"fail", loadRegisters(MOONEYE_FAIL_REGISTERS), TEST_ROM_FAILED},
+		{"timeout", []byte{0x18, 0xFE}, TEST_ROM_TIMED_OUT},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			report := RunTestRom(writeTestRomWithCode(t, test.code), 2)
+			if report.Result != test.expected {
+				t.Errorf("expected %s, got %s", test.expected, report.Result)
+			}
+		})
+	}
+}
+
+// The runner prints a table of results, so running the ROMs shouldn't print
+// anything in between
+func TestRunTestRom_Quiet(t *testing.T) {
+	stdout := os.Stdout
+	defer func() { os.Stdout = stdout }()
+
+	out, err := os.Create(filepath.Join(t.TempDir(), "stdout"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+	os.Stdout = out
+
+	RunTestRom(writeTestRomWithCode(t, []byte{0x18, 0xFE}), 2)
+
+	os.Stdout = stdout
+	if printed, _ := os.ReadFile(out.Name()); len(printed) != 0 {
+		t.Errorf("expected nothing to be printed, got %q", printed)
+	}
+}
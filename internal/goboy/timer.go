@@ -116,3 +116,11 @@ func (timer *Timer) writeByte(address uint16, value byte) {
 		timer.timerBit = timerBitMap[value&0b11]
 	}
 }
+
+func (timer *Timer) saveState(s *stateWriter) {
+	s.write(timer.sysclk, timer.div, timer.tima, timer.tma, timer.tac, timer.timerBit)
+}
+
+func (timer *Timer) loadState(s *stateReader) {
+	s.read(&timer.sysclk, &timer.div, &timer.tima, &timer.tma, &timer.tac, &timer.timerBit)
+}
@@ -0,0 +1,312 @@
+package goboy
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/seashairo/goboy/internal/disasm"
+)
+
+type TraceFormat byte
+
+const (
+	// One line per instruction in the format gameboy-doctor compares against
+	// @see https://github.com/robert/gameboy-doctor
+	TRACE_FORMAT_DOCTOR TraceFormat = iota
+	// Closer to what BGB's debugger shows, with the instruction disassembled
+	TRACE_FORMAT_BGB
+	// A JSON object per line, for feeding into other tools
+	TRACE_FORMAT_JSON
+)
+
+var TRACE_FORMAT_NAMES = map[string]TraceFormat{
+	"doctor": TRACE_FORMAT_DOCTOR,
+	"bgb":    TRACE_FORMAT_BGB,
+	"json":   TRACE_FORMAT_JSON,
+}
+
+func ParseTraceFormat(name string) (TraceFormat, error) {
+	format, ok := TRACE_FORMAT_NAMES[name]
+	if !ok {
+		return 0, fmt.Errorf("unknown trace format %q, expected doctor, bgb or json", name)
+	}
+
+	return format, nil
+}
+
+// TraceOptions pick what gets traced and how. Like Options, start from
+// DefaultTraceOptions rather than the zero value.
+type TraceOptions struct {
+	Format TraceFormat
+	// Only instructions with PC in this range (inclusive) are traced
+	StartPC uint16
+	EndPC   uint16
+	// Only instructions running from this ROM bank are traced, or -1 for all
+	Bank int
+	// Skips this many instructions before tracing anything
+	After uint64
+	// If more than 0, only the last RingSize instructions are kept, and they're
+	// written out when the CPU locks up, the GameBoy is closed, or Dump is
+	// called
+	RingSize int
+	// Adds LY and the PPU mode to each line
+	IncludePPU bool
+	// Adds the number of M-cycles since the Game Boy was switched on
+	IncludeCycles bool
+}
+
+func DefaultTraceOptions() TraceOptions {
+	return TraceOptions{
+		Format: TRACE_FORMAT_DOCTOR,
+		EndPC:  0xFFFF,
+		Bank:   -1,
+	}
+}
+
+// The state of the CPU just before an instruction runs. Formatting is left
+// until it's written, so the ring buffer stays cheap.
+type traceEntry struct {
+	registers CpuRegisters
+	// The 4 bytes from PC, which is as long as any instruction can be
+	pcmem  [4]byte
+	bank   int
+	ly     byte
+	mode   LcdMode
+	cycles uint64
+}
+
+// Writes out the state of the CPU before each instruction it runs. Install one
+// with GameBoy.SetTracer.
+type Tracer struct {
+	options TraceOptions
+	out     *bufio.Writer
+
+	// How many instructions have run, whether they were traced or not
+	instructions uint64
+
+	ring     []traceEntry
+	ringNext int
+	ringFull bool
+
+	// The GameBoy's symbols, for naming things in the BGB and JSON formats
+	symbols *disasm.Symbols
+}
+
+func NewTracer(out io.Writer, options TraceOptions) *Tracer {
+	tracer := &Tracer{
+		options: options,
+		out:     bufio.NewWriter(out),
+	}
+
+	if options.RingSize > 0 {
+		tracer.ring = make([]traceEntry, options.RingSize)
+	}
+
+	return tracer
+}
+
+// Called by the CPU before each instruction
+func (tracer *Tracer) trace(gameboy *GameBoy) {
+	tracer.instructions++
+	if tracer.instructions <= tracer.options.After {
+		return
+	}
+
+	cpu := gameboy.cpu
+	pc := cpu.registers.pc
+	if pc < tracer.options.StartPC || pc > tracer.options.EndPC {
+		return
+	}
+
+	bank := gameboy.cartridge.romBankAt(pc)
+	if tracer.options.Bank >= 0 && bank != tracer.options.Bank {
+		return
+	}
+
+	entry := traceEntry{
+		registers: *cpu.registers,
+		bank:      bank,
+		ly:        gameboy.ppu.lcd.ly,
+		mode:      gameboy.ppu.lcd.GetMode(),
+		cycles:    gameboy.cycles,
+	}
+	for i := range entry.pcmem {
+		entry.pcmem[i] = cpu.bus.peekByte(pc + uint16(i))
+	}
+
+	if tracer.ring == nil {
+		tracer.write(entry)
+		return
+	}
+
+	tracer.ring[tracer.ringNext] = entry
+	tracer.ringNext = (tracer.ringNext + 1) % len(tracer.ring)
+	if tracer.ringNext == 0 {
+		tracer.ringFull = true
+	}
+}
+
+// Writes out and empties the ring buffer, oldest instruction first. Does
+// nothing if the tracer isn't keeping one.
+func (tracer *Tracer) Dump() error {
+	if tracer.ring == nil {
+		return nil
+	}
+
+	if tracer.ringFull {
+		for _, entry := range tracer.ring[tracer.ringNext:] {
+			tracer.write(entry)
+		}
+	}
+	for _, entry := range tracer.ring[:tracer.ringNext] {
+		tracer.write(entry)
+	}
+
+	tracer.ringNext = 0
+	tracer.ringFull = false
+
+	return tracer.Flush()
+}
+
+// Writes out anything that's been buffered
+func (tracer *Tracer) Flush() error {
+	return tracer.out.Flush()
+}
+
+func (tracer *Tracer) write(entry traceEntry) {
+	switch tracer.options.Format {
+	case TRACE_FORMAT_BGB:
+		tracer.writeBgb(entry)
+	case TRACE_FORMAT_JSON:
+		tracer.writeJson(entry)
+	default:
+		tracer.writeDoctor(entry)
+	}
+}
+
+func (tracer *Tracer) writeDoctor(entry traceEntry) {
+	r := entry.registers
+
+	fmt.Fprintf(
+		tracer.out,
+		"A:%2.2X F:%2.2X B:%2.2X C:%2.2X D:%2.2X E:%2.2X H:%2.2X L:%2.2X SP:%4.4X PC:%4.4X PCMEM:%2.2X,%2.2X,%2.2X,%2.2X",
+		r.a, r.f, r.b, r.c, r.d, r.e, r.h, r.l, r.sp, r.pc,
+		entry.pcmem[0], entry.pcmem[1], entry.pcmem[2], entry.pcmem[3],
+	)
+	tracer.writeExtras(entry)
+}
+
+func (tracer *Tracer) writeBgb(entry traceEntry) {
+	r := entry.registers
+	instruction := entry.instruction()
+
+	flags := []byte("----")
+	for i, flag := range []CpuFlag{FLAG_Z, FLAG_N, FLAG_H, FLAG_C} {
+		if r.readFlag(flag) {
+			flags[i] = "ZNHC"[i]
+		}
+	}
+
+	bytes := ""
+	for _, value := range instruction.Bytes {
+		bytes += fmt.Sprintf("%2.2X", value)
+	}
+
+	fmt.Fprintf(
+		tracer.out,
+		"%s: %-6s %-20s A:%2.2X F:%s BC:%4.4X DE:%4.4X HL:%4.4X SP:%4.4X",
+		traceLocation(r.pc, entry.bank), bytes, tracer.symbols.Annotate(instruction, entry.bank),
+		r.a, flags, r.read(R_BC), r.read(R_DE), r.read(R_HL), r.sp,
+	)
+	tracer.writeExtras(entry)
+}
+
+func (tracer *Tracer) writeExtras(entry traceEntry) {
+	if tracer.options.IncludePPU {
+		fmt.Fprintf(tracer.out, " LY:%d MODE:%d", entry.ly, entry.mode)
+	}
+	if tracer.options.IncludeCycles {
+		fmt.Fprintf(tracer.out, " CY:%d", entry.cycles)
+	}
+
+	fmt.Fprintln(tracer.out)
+}
+
+type traceJson struct {
+	PC          uint16  `json:"pc"`
+	Bank        int     `json:"bank"`
+	Symbol      string  `json:"symbol,omitempty"`
+	Instruction string  `json:"instruction"`
+	A           byte    `json:"a"`
+	F           byte    `json:"f"`
+	B           byte    `json:"b"`
+	C           byte    `json:"c"`
+	D           byte    `json:"d"`
+	E           byte    `json:"e"`
+	H           byte    `json:"h"`
+	L           byte    `json:"l"`
+	SP          uint16  `json:"sp"`
+	LY          *byte   `json:"ly,omitempty"`
+	Mode        *byte   `json:"mode,omitempty"`
+	Cycles      *uint64 `json:"cycles,omitempty"`
+}
+
+func (tracer *Tracer) writeJson(entry traceEntry) {
+	r := entry.registers
+
+	line := traceJson{
+		PC:          r.pc,
+		Bank:        entry.bank,
+		Symbol:      tracer.symbols.Nearest(entry.bank, r.pc),
+		Instruction: tracer.symbols.Annotate(entry.instruction(), entry.bank),
+		A:           r.a,
+		F:           r.f,
+		B:           r.b,
+		C:           r.c,
+		D:           r.d,
+		E:           r.e,
+		H:           r.h,
+		L:           r.l,
+		SP:          r.sp,
+	}
+
+	if tracer.options.IncludePPU {
+		mode := byte(entry.mode)
+		line.LY = &entry.ly
+		line.Mode = &mode
+	}
+	if tracer.options.IncludeCycles {
+		line.Cycles = &entry.cycles
+	}
+
+	// Encoder adds the newline
+	json.NewEncoder(tracer.out).Encode(line)
+}
+
+func (entry traceEntry) instruction() disasm.Instruction {
+	pc := entry.registers.pc
+
+	return disasm.Disassemble(func(address uint16) byte {
+		return entry.pcmem[address-pc]
+	}, pc)
+}
+
+// Names where PC is the way BGB does, e.g. ROM1:4000 or WRA0:C000
+func traceLocation(pc uint16, bank int) string {
+	switch {
+	case bank >= 0:
+		return fmt.Sprintf("ROM%X:%4.4X", bank, pc)
+	case pc < 0xA000:
+		return fmt.Sprintf("VRA0:%4.4X", pc)
+	case pc < 0xC000:
+		return fmt.Sprintf("SRA0:%4.4X", pc)
+	case pc < 0xFE00:
+		return fmt.Sprintf("WRA0:%4.4X", pc)
+	case pc >= 0xFF80:
+		return fmt.Sprintf("HRA0:%4.4X", pc)
+	}
+
+	return fmt.Sprintf("%4.4X", pc)
+}
@@ -0,0 +1,188 @@
+package goboy
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// INC A; INC A; INC A; then an invalid opcode
+var TRACE_TEST_PROGRAM = []byte{0x3C, 0x3C, 0x3C, 0xD3}
+
+func runTrace(t *testing.T, options TraceOptions, steps int) []string {
+	t.Helper()
+
+	gameboy := newWramTestGameBoy(t, TRACE_TEST_PROGRAM, false)
+	out := &strings.Builder{}
+	tracer := NewTracer(out, options)
+	gameboy.SetTracer(tracer)
+
+	for range steps {
+		gameboy.Step()
+	}
+
+	if err := tracer.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	return strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+}
+
+func TestTrace_Doctor(t *testing.T) {
+	lines := runTrace(t, DefaultTraceOptions(), 2)
+
+	if len(lines) != 2 {
+		t.Fatalf("expected a line per instruction, got %q", lines)
+	}
+
+	// Each line is the state before the instruction runs
+	expected := "A:01 F:10 B:00 C:13 D:00 E:D8 H:01 L:4D SP:DFF0 PC:C001 PCMEM:3C,3C,D3,00"
+	if lines[1] != expected {
+		t.Errorf("expected %q, got %q", expected, lines[1])
+	}
+}
+
+func TestTrace_Bgb(t *testing.T) {
+	options := DefaultTraceOptions()
+	options.Format = TRACE_FORMAT_BGB
+	options.IncludePPU = true
+	options.IncludeCycles = true
+	lines := runTrace(t, options, 1)
+
+	expected := "WRA0:C000: 3C     INC A                A:00 F:Z-HC BC:0013 DE:00D8 HL:014D SP:DFF0 LY:"
+	if !strings.HasPrefix(lines[0], expected) || !strings.Contains(lines[0], " MODE:") || !strings.Contains(lines[0], " CY:") {
+		t.Errorf("expected %q..., got %q", expected, lines[0])
+	}
+}
+
+func TestTrace_Json(t *testing.T) {
+	options := DefaultTraceOptions()
+	options.Format = TRACE_FORMAT_JSON
+	lines := runTrace(t, options, 3)
+
+	var line map[string]any
+	if err := json.Unmarshal([]byte(lines[2]), &line); err != nil {
+		t.Fatal(err)
+	}
+
+	if line["pc"] != float64(0xC002) || line["a"] != float64(2) || line["instruction"] != "INC A" || line["bank"] != float64(-1) {
+		t.Errorf("unexpected JSON trace line %s", lines[2])
+	}
+	if _, ok := line["ly"]; ok {
+		t.Errorf("expected no PPU state unless asked for, got %s", lines[2])
+	}
+}
+
+func TestTrace_Filters(t *testing.T) {
+	options := DefaultTraceOptions()
+	options.StartPC = 0xC001
+	options.EndPC = 0xC002
+	lines := runTrace(t, options, 3)
+
+	if len(lines) != 2 || !strings.Contains(lines[0], "PC:C001") || !strings.Contains(lines[1], "PC:C002") {
+		t.Errorf("expected only PC 0xC001 to 0xC002, got %q", lines)
+	}
+
+	options = DefaultTraceOptions()
+	options.After = 2
+	lines = runTrace(t, options, 3)
+
+	if len(lines) != 1 || !strings.Contains(lines[0], "PC:C002") {
+		t.Errorf("expected the first 2 instructions to be skipped, got %q", lines)
+	}
+
+	options = DefaultTraceOptions()
+	options.Bank = 0
+	lines = runTrace(t, options, 3)
+
+	if len(lines) != 1 || lines[0] != "" {
+		t.Errorf("expected nothing running from WRAM to be traced for bank 0, got %q", lines)
+	}
+}
+
+func TestTrace_RingDumpsOnLockup(t *testing.T) {
+	options := DefaultTraceOptions()
+	options.RingSize = 2
+
+	gameboy := newWramTestGameBoy(t, TRACE_TEST_PROGRAM, false)
+	out := &strings.Builder{}
+	gameboy.SetTracer(NewTracer(out, options))
+
+	for range 3 {
+		gameboy.Step()
+	}
+	if out.Len() != 0 {
+		t.Fatalf("expected nothing to be written before a fault, got %q", out.String())
+	}
+
+	gameboy.Step()
+
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[0], "PC:C002") || !strings.Contains(lines[1], "PC:C003") {
+		t.Errorf("expected the last 2 instructions, got %q", lines)
+	}
+}
+
+func TestTrace_RingDumpsOnClose(t *testing.T) {
+	options := DefaultOptions()
+	options.RomPath = writeTestRom(t, 0x00, 0x00, 0x00)
+	options.TracePath = filepath.Join(t.TempDir(), "trace.log")
+	options.Trace.RingSize = 2
+
+	gameboy, err := NewGameBoy(options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for range 3 {
+		gameboy.Step()
+	}
+
+	if err := gameboy.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	trace, err := os.ReadFile(options.TracePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if lines := strings.Count(string(trace), "\n"); lines != 2 {
+		t.Errorf("expected the last 2 instructions to be written on close, got %q", trace)
+	}
+}
+
+func TestTrace_FileIsClosed(t *testing.T) {
+	options := DefaultOptions()
+	options.RomPath = writeTestRom(t, 0x00, 0x00, 0x00)
+	options.TracePath = filepath.Join(t.TempDir(), "trace.log")
+
+	gameboy, err := NewGameBoy(options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for range 3 {
+		gameboy.Step()
+	}
+
+	if err := gameboy.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	trace, err := os.ReadFile(options.TracePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if lines := strings.Count(string(trace), "\n"); lines != 3 {
+		t.Errorf("expected the trace to be flushed on close, got %q", trace)
+	}
+
+	// Closing twice is harmless
+	if err := gameboy.Close(); err != nil {
+		t.Errorf("expected closing again to do nothing, got %v", err)
+	}
+}
@@ -14,4 +14,7 @@ func Uint16ToBytes(value uint16) (byte, byte) {
 type MemoryBusser interface {
 	readByte(address uint16) byte
 	writeByte(address uint16, value byte)
+	// Same as readByte and writeByte, but without calling memory hooks
+	peekByte(address uint16) byte
+	pokeByte(address uint16, value byte)
 }
@@ -0,0 +1,42 @@
+package ui
+
+import (
+	"fmt"
+
+	"github.com/seashairo/goboy/internal/goboy"
+)
+
+// Runs the emulator on its own goroutine, with the SDL window and audio device
+// on this one until the window is closed
+func Emulate(options goboy.Options) error {
+	gameboy, err := goboy.NewGameBoy(options)
+	if err != nil {
+		return err
+	}
+
+	// The game has crashed, but the window stays open so the screen can still
+	// be seen
+	gameboy.RegisterLockupCallback(func(fault goboy.LockupFault) {
+		fmt.Println(fault)
+	})
+
+	// The GameBoy flushes battery saves as it stops, so make sure it has
+	// finished before returning
+	stopped := make(chan struct{})
+	go func() {
+		gameboy.Run()
+		close(stopped)
+	}()
+
+	ui := NewUI(gameboy, options)
+	defer ui.Destroy()
+
+	for ui.running {
+		ui.Update()
+	}
+
+	gameboy.Stop()
+	<-stopped
+
+	return nil
+}
@@ -1,4 +1,4 @@
-package goboy
+package ui
 
 // typedef unsigned char Uint8;
 // void AudioCallback(void *userdata, Uint8 *stream, int len);
@@ -7,13 +7,12 @@ import "C"
 import (
 	"unsafe"
 
+	"github.com/seashairo/goboy/internal/goboy"
 	"github.com/veandco/go-sdl2/sdl"
 )
 
 var PALLETTE = [4]uint32{0xFFFFFFFF, 0xFFAAAAAA, 0xFF555555, 0xFF000000}
 
-var scale = int32(2)
-
 const TILE_HEIGHT = 8
 const TILE_WIDTH = 8
 const TILES_X = 16
@@ -21,7 +20,8 @@ const TILES_Y = 24
 
 type UI struct {
 	running bool
-	gameboy *GameBoy
+	gameboy *goboy.GameBoy
+	scale   int32
 
 	lcdWindow   *sdl.Window
 	lcdRenderer *sdl.Renderer
@@ -45,10 +45,11 @@ type UI struct {
 	audioBuffer   []int16
 }
 
-func NewUI(gameboy *GameBoy) *UI {
+func NewUI(gameboy *goboy.GameBoy, options goboy.Options) *UI {
 	ui := &UI{
 		running:       true,
 		gameboy:       gameboy,
+		scale:         int32(max(options.Scale, 1)),
 		previousFrame: 0,
 	}
 
@@ -61,7 +62,9 @@ func NewUI(gameboy *GameBoy) *UI {
 	}
 
 	ui.initLcd()
-	ui.initTileDebug()
+	if options.DebugWindows {
+		ui.initTileDebug()
+	}
 	// ui.initAudioDebug()
 	ui.initAudio()
 
@@ -70,8 +73,12 @@ func NewUI(gameboy *GameBoy) *UI {
 
 func (ui *UI) Update() {
 	ui.handleEvents()
-	ui.updateTileDebugWindow()
-	ui.updateLcdWindow()
+
+	frame := ui.gameboy.LatestFrame()
+	if ui.tileDebugWindow != nil {
+		ui.updateTileDebugWindow(frame)
+	}
+	ui.updateLcdWindow(frame)
 	// ui.updateAudioDebugWindow()
 }
 
@@ -80,17 +87,17 @@ const (
 )
 
 func (ui *UI) queueAudio(left int16, right int16) {
-	if len(ui.audioBuffer) <= sampleRate*2 {
+	if len(ui.audioBuffer) <= goboy.SAMPLE_RATE*2 {
 		ui.audioBuffer = append(ui.audioBuffer, left, right)
 
 		// ui.lastAudioSamples = append(ui.lastAudioSamples, left)
-		// if len(ui.lastAudioSamples) > sampleRate {
+		// if len(ui.lastAudioSamples) > goboy.SAMPLE_RATE {
 		// 	ui.lastAudioSamples = ui.lastAudioSamples[1:]
 		// }
 	}
 
 	// 0.25s worth of audio queued up
-	if sdl.GetQueuedAudioSize(ui.audioDeviceId) > sampleRate/4 {
+	if sdl.GetQueuedAudioSize(ui.audioDeviceId) > goboy.SAMPLE_RATE/4 {
 		return
 	}
 
@@ -109,7 +116,7 @@ func (ui *UI) queueAudio(left int16, right int16) {
 
 func (ui *UI) initAudio() {
 	spec := sdl.AudioSpec{
-		Freq:     sampleRate,
+		Freq:     goboy.SAMPLE_RATE,
 		Format:   sdl.AUDIO_S16SYS, // Signed 16-bit samples in system byte order
 		Channels: 2,                // Stereo
 		Samples:  bufferSize,       // Buffer size (affects the latency)
@@ -131,12 +138,12 @@ func (ui *UI) initAudio() {
 }
 
 // @see https://gbdev.io/pandocs/Tile_Data.html
-func (ui *UI) displayTile(tileNum uint16, xDraw int32, yDraw int32) {
+func (ui *UI) displayTile(frame *goboy.Frame, tileNum uint16, xDraw int32, yDraw int32) {
 	// Each tile occupies 16 bytes
 	for y := int32(0); y < 16; y += 2 {
 		// Where each line is represented by 2 bytes
-		b1 := ui.gameboy.bus.readByte(VIDEO_RAM_START + tileNum*TILES_X + uint16(y))
-		b2 := ui.gameboy.bus.readByte(VIDEO_RAM_START + tileNum*TILES_X + uint16(y) + 1)
+		b1 := frame.VideoRam[tileNum*TILES_X+uint16(y)]
+		b2 := frame.VideoRam[tileNum*TILES_X+uint16(y)+1]
 
 		for bit := 7; bit >= 0; bit-- {
 			// For each line, the first byte specifies the least significant bit of
@@ -148,10 +155,10 @@ func (ui *UI) displayTile(tileNum uint16, xDraw int32, yDraw int32) {
 			color := hi | lo
 
 			rect := sdl.Rect{
-				X: xDraw + (7-int32(bit))*scale,
-				Y: yDraw + (y/2)*scale,
-				W: scale,
-				H: scale,
+				X: xDraw + (7-int32(bit))*ui.scale,
+				Y: yDraw + (y/2)*ui.scale,
+				W: ui.scale,
+				H: ui.scale,
 			}
 
 			ui.tileDebugSurface.FillRect(&rect, PALLETTE[color])
@@ -159,7 +166,7 @@ func (ui *UI) displayTile(tileNum uint16, xDraw int32, yDraw int32) {
 	}
 }
 
-func (ui *UI) updateTileDebugWindow() {
+func (ui *UI) updateTileDebugWindow(frame *goboy.Frame) {
 	surface := ui.tileDebugSurface
 
 	rect := sdl.Rect{X: 0, Y: 0, W: surface.W, H: surface.H}
@@ -170,11 +177,11 @@ func (ui *UI) updateTileDebugWindow() {
 
 	for y := int32(0); y < TILES_Y; y++ {
 		for x := int32(0); x < TILES_X; x++ {
-			ui.displayTile(tileNum, xDraw+(x*scale), yDraw+(y*scale))
-			xDraw += TILE_WIDTH * scale
+			ui.displayTile(frame, tileNum, xDraw+(x*ui.scale), yDraw+(y*ui.scale))
+			xDraw += TILE_WIDTH * ui.scale
 			tileNum++
 		}
-		yDraw += TILE_HEIGHT * scale
+		yDraw += TILE_HEIGHT * ui.scale
 		xDraw = 0
 	}
 
@@ -185,8 +192,8 @@ func (ui *UI) updateTileDebugWindow() {
 }
 
 func (ui *UI) initTileDebug() {
-	tileDebugWidth := (TILES_X * TILE_WIDTH * scale) + (TILES_X * scale) - scale
-	tileDebugHeight := (TILES_Y * TILE_HEIGHT * scale) + (TILES_Y * scale) - scale
+	tileDebugWidth := (TILES_X * TILE_WIDTH * ui.scale) + (TILES_X * ui.scale) - ui.scale
+	tileDebugHeight := (TILES_Y * TILE_HEIGHT * ui.scale) + (TILES_Y * ui.scale) - ui.scale
 	tileDebugWindow, tileDebugRenderer, err := sdl.CreateWindowAndRenderer(tileDebugWidth, tileDebugHeight, 0)
 	if err != nil {
 		panic(err)
@@ -237,7 +244,7 @@ func (ui *UI) initTileDebug() {
 // }
 
 // func (ui *UI) initAudioDebug() {
-// 	ui.lastAudioSamples = make([]int16, sampleRate)
+// 	ui.lastAudioSamples = make([]int16, goboy.SAMPLE_RATE)
 
 // 	audioDebugWindow, audioDebugRenderer, err := sdl.CreateWindowAndRenderer(1000, 100, 0)
 // 	if err != nil {
@@ -252,7 +259,7 @@ func (ui *UI) initTileDebug() {
 
 // 	x, y := ui.lcdWindow.GetPosition()
 
-// 	audioDebugWindow.SetPosition(x+LCD_WIDTH*scale, y)
+// 	audioDebugWindow.SetPosition(x+LCD_WIDTH*ui.scale, y)
 // 	audioDebugSurface, err := audioDebugWindow.GetSurface()
 // 	if err != nil {
 // 		panic(err)
@@ -265,8 +272,8 @@ func (ui *UI) initTileDebug() {
 // 	ui.audioDebugTexture = audioDebugTexture
 // }
 
-func (ui *UI) updateLcdWindow() {
-	if ui.previousFrame == ui.gameboy.ppu.currentFrame {
+func (ui *UI) updateLcdWindow(frame *goboy.Frame) {
+	if ui.previousFrame == frame.Number {
 		return
 	}
 
@@ -275,17 +282,17 @@ func (ui *UI) updateLcdWindow() {
 	surfaceRect := sdl.Rect{X: 0, Y: 0, W: surface.W, H: surface.H}
 	surface.FillRect(&surfaceRect, 0xFFFF0000)
 
-	for lineNum := int32(0); lineNum < LCD_HEIGHT; lineNum++ {
-		for x := int32(0); x < LCD_WIDTH; x++ {
+	for lineNum := int32(0); lineNum < goboy.LCD_HEIGHT; lineNum++ {
+		for x := int32(0); x < goboy.LCD_WIDTH; x++ {
 			rect := sdl.Rect{
-				X: x * scale,
-				Y: lineNum * scale,
-				W: scale,
-				H: scale,
+				X: x * ui.scale,
+				Y: lineNum * ui.scale,
+				W: ui.scale,
+				H: ui.scale,
 			}
 
-			index := x + (lineNum * LCD_WIDTH)
-			pixel := ui.gameboy.ppu.videoBuffer[index]
+			index := x + (lineNum * goboy.LCD_WIDTH)
+			pixel := frame.Pixels[index]
 
 			surface.FillRect(&rect, pixel)
 		}
@@ -297,10 +304,10 @@ func (ui *UI) updateLcdWindow() {
 
 	// ui.lcdRenderer.SetDrawColor(0, 0, 255, 255)
 	// windowRect2 := sdl.Rect{
-	// 	X: int32(ui.gameboy.bus.readByte(LCD_WX)) * scale,
-	// 	Y: int32(ui.gameboy.bus.readByte(LCD_WY)) * scale,
-	// 	W: 256 * scale,
-	// 	H: 256 * scale,
+	// 	X: int32(ui.gameboy.bus.readByte(LCD_WX)) * ui.scale,
+	// 	Y: int32(ui.gameboy.bus.readByte(LCD_WY)) * ui.scale,
+	// 	W: 256 * ui.scale,
+	// 	H: 256 * ui.scale,
 	// }
 	// ui.lcdRenderer.DrawRect(&windowRect2)
 
@@ -308,8 +315,8 @@ func (ui *UI) updateLcdWindow() {
 }
 
 func (ui *UI) initLcd() {
-	lcdWidth := LCD_WIDTH * scale
-	lcdHeight := LCD_HEIGHT * scale
+	lcdWidth := goboy.LCD_WIDTH * ui.scale
+	lcdHeight := goboy.LCD_HEIGHT * ui.scale
 
 	lcdWindow, lcdRenderer, err := sdl.CreateWindowAndRenderer(lcdWidth, lcdHeight, 0)
 	if err != nil {
@@ -339,24 +346,28 @@ func (ui *UI) handleEvents() {
 	for event := sdl.PollEvent(); event != nil; event = sdl.PollEvent() {
 		switch t := event.(type) {
 		case sdl.KeyboardEvent:
-			var b Button = 255
+			if ui.handleSaveStateKey(t) {
+				continue
+			}
+
+			var b goboy.Button = 255
 			switch t.Keysym.Sym {
 			case sdl.K_z:
-				b = JOYPAD_A
+				b = goboy.JOYPAD_A
 			case sdl.K_x:
-				b = JOYPAD_B
+				b = goboy.JOYPAD_B
 			case sdl.K_BACKSPACE:
-				b = JOYPAD_SELECT
+				b = goboy.JOYPAD_SELECT
 			case sdl.K_RETURN:
-				b = JOYPAD_START
+				b = goboy.JOYPAD_START
 			case sdl.K_RIGHT:
-				b = JOYPAD_RIGHT
+				b = goboy.JOYPAD_RIGHT
 			case sdl.K_LEFT:
-				b = JOYPAD_LEFT
+				b = goboy.JOYPAD_LEFT
 			case sdl.K_UP:
-				b = JOYPAD_UP
+				b = goboy.JOYPAD_UP
 			case sdl.K_DOWN:
-				b = JOYPAD_DOWN
+				b = goboy.JOYPAD_DOWN
 			}
 
 			if b == 255 {
@@ -379,9 +390,38 @@ func (ui *UI) handleEvents() {
 	}
 }
 
+var SAVE_STATE_SLOT_KEYS = []sdl.Keycode{
+	sdl.K_F1, sdl.K_F2, sdl.K_F3, sdl.K_F4, sdl.K_F5, sdl.K_F6, sdl.K_F7, sdl.K_F8, sdl.K_F9,
+}
+
+// F1-F9 load from the matching save state slot, and holding shift saves to it
+// instead
+func (ui *UI) handleSaveStateKey(event sdl.KeyboardEvent) bool {
+	for index, key := range SAVE_STATE_SLOT_KEYS {
+		if event.Keysym.Sym != key {
+			continue
+		}
+
+		if event.Type == sdl.KEYDOWN && event.Repeat == 0 {
+			slot := index + 1
+			if event.Keysym.Mod&sdl.KMOD_SHIFT != 0 {
+				ui.gameboy.SaveStateSlot(slot)
+			} else {
+				ui.gameboy.LoadStateSlot(slot)
+			}
+		}
+
+		return true
+	}
+
+	return false
+}
+
 func (ui *UI) Destroy() {
 	ui.lcdWindow.Destroy()
-	ui.tileDebugWindow.Destroy()
+	if ui.tileDebugWindow != nil {
+		ui.tileDebugWindow.Destroy()
+	}
 
 	sdl.PauseAudioDevice(ui.audioDeviceId, false)
 	sdl.CloseAudioDevice(ui.audioDeviceId)